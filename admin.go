@@ -0,0 +1,243 @@
+package xlog
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/whencome/xlog/def"
+	"github.com/whencome/xlog/util"
+)
+
+// LoggerInfo 日志对象的运行状态，由AdminHandler返回
+type LoggerInfo struct {
+	Name     string       `json:"name"`                // 日志对象名称
+	Level    string       `json:"level"`               // 日志等级
+	Switch   string       `json:"switch"`              // 开关，on或者off
+	Outputs  []OutputInfo `json:"outputs"`             // 输出目标
+	RevertAt *time.Time   `json:"revert_at,omitempty"` // 临时修改的配置恢复的时间
+}
+
+// OutputInfo 输出目标的运行状态
+type OutputInfo struct {
+	Output string `json:"output"`         // 输出类型，stdout,stderr,file,sink
+	Level  string `json:"level"`          // 输出的最低日志等级
+	File   string `json:"file,omitempty"` // 当前写入的日志文件
+}
+
+// adminRevert 等待恢复的临时配置修改
+type adminRevert struct {
+	timer  *time.Timer
+	at     time.Time    // 恢复的时间
+	prev   *loggerState // 修改之前的配置快照
+	target *loggerState // 修改之后的配置快照，配置被其他方式更新后不再恢复
+}
+
+// AdminHandler 返回用于在运行时查看以及修改日志对象的http.Handler
+//
+//	GET              列出全部日志对象，指定name参数时只返回该日志对象
+//	PUT/POST         修改日志对象的等级以及开关，参数：
+//	                   name   日志对象名称，必填
+//	                   level  日志等级：trace,debug,info,warn,error,fatal以及自定义的日志等级
+//	                          有多个输出目标时，各个输出目标仍然只输出不低于其自身日志等级的日志
+//	                   switch 开关：on,off
+//	                   ttl    修改的有效时间，如10m，到期后恢复之前的配置，为空表示永久有效
+//
+// 如：curl -X PUT 'http://127.0.0.1:8080/debug/xlog?name=payment&level=debug&ttl=10m'
+// 该接口可以修改日志配置，应当只在内部端口上提供
+func AdminHandler() http.Handler {
+	return std.AdminHandler()
+}
+
+// AdminHandler 返回用于在运行时查看以及修改注册表中日志对象的http.Handler，参考包级别的AdminHandler
+func (reg *Registry) AdminHandler() http.Handler {
+	return http.HandlerFunc(reg.serveAdmin)
+}
+
+// serveAdmin 处理查看以及修改日志对象的请求
+func (reg *Registry) serveAdmin(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		name := r.FormValue("name")
+		if name == "" {
+			writeAdminJson(w, http.StatusOK, reg.listLoggerInfo())
+			return
+		}
+		l := reg.adminLogger(name)
+		if l == nil {
+			writeAdminError(w, http.StatusNotFound, "logger %q not found", name)
+			return
+		}
+		writeAdminJson(w, http.StatusOK, reg.loggerInfo(name, l))
+	case http.MethodPut, http.MethodPost:
+		name := r.FormValue("name")
+		l := reg.adminLogger(name)
+		if l == nil {
+			writeAdminError(w, http.StatusNotFound, "logger %q not found", name)
+			return
+		}
+		level, sw, ttlStr := r.FormValue("level"), r.FormValue("switch"), r.FormValue("ttl")
+		if level == "" && sw == "" {
+			writeAdminError(w, http.StatusBadRequest, "level or switch is required")
+			return
+		}
+		if level != "" && !util.IsLogLevel(level) {
+			writeAdminError(w, http.StatusBadRequest, "invalid level %q", level)
+			return
+		}
+		if sw != "" && sw != "on" && sw != "off" {
+			writeAdminError(w, http.StatusBadRequest, "invalid switch %q", sw)
+			return
+		}
+		var ttl time.Duration
+		if ttlStr != "" {
+			var err error
+			ttl, err = util.ParseDuration(ttlStr)
+			if err != nil || ttl <= 0 {
+				writeAdminError(w, http.StatusBadRequest, "invalid ttl %q", ttlStr)
+				return
+			}
+		}
+		reg.adminUpdate(l, level, sw, ttl)
+		writeAdminJson(w, http.StatusOK, reg.loggerInfo(name, l))
+	default:
+		w.Header().Set("Allow", "GET, PUT, POST")
+		writeAdminError(w, http.StatusMethodNotAllowed, "method %s not allowed", r.Method)
+	}
+}
+
+// adminLogger 获取指定名称的日志对象，未注册default时使用默认日志对象
+func (reg *Registry) adminLogger(name string) *StdLogger {
+	if name == "" {
+		return nil
+	}
+	if l := reg.MustUse(name); l != nil {
+		return l
+	}
+	if name == "default" {
+		return reg.defaultLogger
+	}
+	return nil
+}
+
+// adminUpdate 修改日志对象的等级以及开关，ttl大于0时到期后恢复修改之前的配置
+// 存在尚未恢复的临时修改时，恢复到最早一次临时修改之前的配置
+func (reg *Registry) adminUpdate(l *StdLogger, level, sw string, ttl time.Duration) {
+	reg.adminMu.Lock()
+	defer reg.adminMu.Unlock()
+	prev := l.load()
+	if rv, ok := reg.adminReverts[l.loggerCore]; ok {
+		rv.timer.Stop()
+		delete(reg.adminReverts, l.loggerCore)
+		if l.load() == rv.target {
+			prev = rv.prev
+		}
+	}
+	l.update(func(d *LogDefinition) {
+		if level != "" {
+			d.Level = util.NumLogLevel(level)
+		}
+		if sw != "" {
+			d.Disabled = sw == "off"
+		}
+	})
+	if ttl <= 0 {
+		return
+	}
+	rv := &adminRevert{at: time.Now().Add(ttl), prev: prev, target: l.load()}
+	rv.timer = time.AfterFunc(ttl, func() {
+		reg.adminMu.Lock()
+		defer reg.adminMu.Unlock()
+		if reg.adminReverts[l.loggerCore] != rv {
+			return
+		}
+		delete(reg.adminReverts, l.loggerCore)
+		l.swapState(rv.target, rv.prev)
+	})
+	reg.adminReverts[l.loggerCore] = rv
+}
+
+// swapState 当前的配置快照为old时替换为state，配置已经被其他方式更新时不做处理
+func (l *StdLogger) swapState(old, state *loggerState) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.load() != old {
+		return false
+	}
+	l.state.Store(state)
+	return true
+}
+
+// listLoggerInfo 列出全部日志对象的运行状态，按名称排序
+func (reg *Registry) listLoggerInfo() []*LoggerInfo {
+	infos := make([]*LoggerInfo, 0)
+	hasDefault := false
+	reg.loggers.Range(func(key, value interface{}) bool {
+		k, ok1 := key.(string)
+		l, ok2 := value.(*StdLogger)
+		if ok1 && ok2 && l != nil {
+			infos = append(infos, reg.loggerInfo(k, l))
+			hasDefault = hasDefault || k == "default"
+		}
+		return true
+	})
+	if !hasDefault {
+		infos = append(infos, reg.loggerInfo("default", reg.defaultLogger))
+	}
+	sort.Slice(infos, func(i, j int) bool {
+		return infos[i].Name < infos[j].Name
+	})
+	return infos
+}
+
+// loggerInfo 获取日志对象的运行状态
+func (reg *Registry) loggerInfo(name string, l *StdLogger) *LoggerInfo {
+	st := l.load()
+	info := &LoggerInfo{
+		Name:    name,
+		Level:   util.LogLevelName(st.def.Level),
+		Switch:  "on",
+		Outputs: make([]OutputInfo, 0, len(st.outputs)),
+	}
+	if st.def.Disabled {
+		info.Switch = "off"
+	}
+	for _, o := range st.outputs {
+		oi := OutputInfo{Level: util.LogLevelName(o.def.Level)}
+		switch o.outputType {
+		case def.LogToStdout:
+			oi.Output = "stdout"
+		case def.LogToStderr:
+			oi.Output = "stderr"
+		case def.LogToFile:
+			oi.Output = "file"
+		default:
+			oi.Output = "sink"
+		}
+		if fileSink, ok := o.sink.(*FileSink); ok {
+			oi.File = fileSink.Path()
+		}
+		info.Outputs = append(info.Outputs, oi)
+	}
+	reg.adminMu.Lock()
+	if rv, ok := reg.adminReverts[l.loggerCore]; ok && rv.target == st {
+		at := rv.at
+		info.RevertAt = &at
+	}
+	reg.adminMu.Unlock()
+	return info
+}
+
+// writeAdminJson 返回JSON格式的响应
+func writeAdminJson(w http.ResponseWriter, code int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(code)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+// writeAdminError 返回JSON格式的错误信息
+func writeAdminError(w http.ResponseWriter, code int, format string, v ...interface{}) {
+	writeAdminJson(w, code, map[string]string{"error": fmt.Sprintf(format, v...)})
+}
@@ -0,0 +1,149 @@
+package xlog
+
+import (
+	"sync"
+
+	"github.com/whencome/xlog/def"
+)
+
+// 默认的异步日志队列长度
+const defaultQueueSize = 1024
+
+// asyncItem 异步队列中的一条日志
+type asyncItem struct {
+	level int           // 日志等级，用于按等级丢弃日志
+	out   *loggerOutput // 输出目标
+	data  []byte        // 已经编码的日志内容
+}
+
+// asyncWriter 异步写日志，日志先写入有界的环形队列，由后台协程写入输出目标
+type asyncWriter struct {
+	mu        sync.Mutex
+	notEmpty  *sync.Cond
+	notFull   *sync.Cond
+	idle      *sync.Cond
+	items     []asyncItem // 环形队列
+	head      int         // 队首位置
+	count     int         // 队列中的日志数量
+	policy    int         // 队列满时的处理方式
+	dropLevel int         // 队列满时丢弃低于此等级的日志，仅当policy为OverflowDropBelow时有效
+	dropped   uint64      // 丢弃的日志数量
+	writing   bool        // 后台协程是否正在写日志
+	closed    bool
+	done      chan struct{}
+}
+
+// newAsyncWriter 创建异步写日志对象，并启动后台协程
+func newAsyncWriter(d *LogDefinition) *asyncWriter {
+	size := d.QueueSize
+	if size <= 0 {
+		size = defaultQueueSize
+	}
+	w := &asyncWriter{
+		items:     make([]asyncItem, size),
+		policy:    d.Overflow,
+		dropLevel: d.OverflowLevel,
+		done:      make(chan struct{}),
+	}
+	w.notEmpty = sync.NewCond(&w.mu)
+	w.notFull = sync.NewCond(&w.mu)
+	w.idle = sync.NewCond(&w.mu)
+	go w.run()
+	return w
+}
+
+// put 将日志放入队列，队列已满时按照配置的方式处理
+func (w *asyncWriter) put(level int, out *loggerOutput, data []byte) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	for w.count == len(w.items) && !w.closed {
+		switch w.policy {
+		case def.OverflowDropNewest:
+			w.dropped++
+			return
+		case def.OverflowDropOldest:
+			w.items[w.head] = asyncItem{}
+			w.head = (w.head + 1) % len(w.items)
+			w.count--
+			w.dropped++
+		case def.OverflowDropBelow:
+			if level < w.dropLevel {
+				w.dropped++
+				return
+			}
+			w.notFull.Wait()
+		default:
+			w.notFull.Wait()
+		}
+	}
+	if w.closed {
+		w.dropped++
+		return
+	}
+	w.items[(w.head+w.count)%len(w.items)] = asyncItem{level: level, out: out, data: data}
+	w.count++
+	w.notEmpty.Signal()
+}
+
+// run 后台协程，每次取出队列中的全部日志写入输出目标
+func (w *asyncWriter) run() {
+	defer close(w.done)
+	batch := make([]asyncItem, 0, len(w.items))
+	for {
+		w.mu.Lock()
+		for w.count == 0 && !w.closed {
+			w.notEmpty.Wait()
+		}
+		if w.count == 0 && w.closed {
+			w.mu.Unlock()
+			return
+		}
+		batch = batch[:0]
+		for w.count > 0 {
+			batch = append(batch, w.items[w.head])
+			w.items[w.head] = asyncItem{}
+			w.head = (w.head + 1) % len(w.items)
+			w.count--
+		}
+		w.writing = true
+		w.notFull.Broadcast()
+		w.mu.Unlock()
+
+		for _, item := range batch {
+			if item.out.sink != nil {
+				_, _ = item.out.sink.Write(item.data)
+			}
+		}
+
+		w.mu.Lock()
+		w.writing = false
+		w.idle.Broadcast()
+		w.mu.Unlock()
+	}
+}
+
+// drain 等待队列中的日志全部写入输出目标
+func (w *asyncWriter) drain() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	for w.count > 0 || w.writing {
+		w.idle.Wait()
+	}
+}
+
+// close 写入队列中剩余的日志后停止后台协程
+func (w *asyncWriter) close() {
+	w.mu.Lock()
+	w.closed = true
+	w.notEmpty.Broadcast()
+	w.notFull.Broadcast()
+	w.mu.Unlock()
+	<-w.done
+}
+
+// droppedCount 返回丢弃的日志数量
+func (w *asyncWriter) droppedCount() uint64 {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.dropped
+}
@@ -0,0 +1,114 @@
+package xlog
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+// FileConfig 配置文件的内容，包括默认日志对象以及按名称定义的多个日志对象
+// 以yaml为例：
+//
+//	default:
+//	  output: stdout
+//	  log_level: info
+//	loggers:
+//	  order:
+//	    log_path: /home/logs
+//	    log_prefix: order_
+//	    output: file
+type FileConfig struct {
+	Default *Config            `json:"default" toml:"default" yaml:"default"` // 默认日志对象的配置
+	Loggers map[string]*Config `json:"loggers" toml:"loggers" yaml:"loggers"` // 按名称定义的日志对象配置
+}
+
+// LoadConfigFile 读取日志配置文件，根据文件后缀识别格式，支持.json、.toml、.yaml以及.yml
+// 配置文件中存在未定义的字段或者配置项的值无效时返回错误，避免写错的配置被忽略
+func LoadConfigFile(path string) (*FileConfig, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	fc := &FileConfig{}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		dec := json.NewDecoder(bytes.NewReader(data))
+		dec.DisallowUnknownFields()
+		err = dec.Decode(fc)
+	case ".toml":
+		var md toml.MetaData
+		md, err = toml.Decode(string(data), fc)
+		if err == nil && len(md.Undecoded()) > 0 {
+			err = fmt.Errorf("unknown fields %v", md.Undecoded())
+		}
+	case ".yaml", ".yml":
+		dec := yaml.NewDecoder(bytes.NewReader(data))
+		dec.KnownFields(true)
+		err = dec.Decode(fc)
+		if err == io.EOF {
+			// 空文件
+			err = nil
+		}
+	default:
+		return nil, fmt.Errorf("xlog: unsupported config file format: %s", path)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("xlog: parse config file %s failed: %v", path, err)
+	}
+	if err = fc.Validate(); err != nil {
+		return nil, fmt.Errorf("xlog: config file %s: %v", path, err)
+	}
+	return fc, nil
+}
+
+// InitFromFile 读取日志配置文件，并注册其中定义的全部日志对象
+// default对应的配置注册为默认日志对象，与Init效果相同
+// 配置文件中的配置使用前缀为EnvPrefix的环境变量覆盖，参考ApplyEnv
+func InitFromFile(path string) error {
+	return std.InitFromFile(path)
+}
+
+// InitFromFile 读取日志配置文件，并在注册表中注册其中定义的全部日志对象
+func (r *Registry) InitFromFile(path string) error {
+	fc, err := loadConfigFileEnv(path)
+	if err != nil {
+		return err
+	}
+	return fc.register(r)
+}
+
+// loadConfigFileEnv 读取日志配置文件，并使用前缀为EnvPrefix的环境变量覆盖其中的配置
+func loadConfigFileEnv(path string) (*FileConfig, error) {
+	fc, err := LoadConfigFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if err := fc.ApplyEnv(EnvPrefix); err != nil {
+		return nil, err
+	}
+	return fc, nil
+}
+
+// register 在注册表中注册配置文件中定义的全部日志对象，返回第一个注册失败的错误
+func (fc *FileConfig) register(r *Registry) error {
+	var err error
+	if fc.Default != nil {
+		err = r.InitE(fc.Default)
+	}
+	for k, cfg := range fc.Loggers {
+		if cfg == nil {
+			continue
+		}
+		if e := r.RegisterE(k, cfg); e != nil && err == nil {
+			err = e
+		}
+	}
+	return err
+}
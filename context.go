@@ -0,0 +1,181 @@
+package xlog
+
+import (
+	"context"
+	"sync"
+
+	"github.com/whencome/xlog/def"
+)
+
+// ctxFieldsKey context中保存日志字段的键
+type ctxFieldsKey struct{}
+
+// ContextExtractor 从context中提取日志字段，返回键值对，如 []interface{}{"trace_id", id}
+type ContextExtractor func(ctx context.Context) []interface{}
+
+// 已注册的context字段提取函数
+var (
+	extractorMu       sync.RWMutex
+	contextExtractors []ContextExtractor
+)
+
+// NewContext 返回一个附加了日志字段的context，kv为键值对
+// 使用该context记录的日志（如InfoContext）都会自动附加这些字段
+func NewContext(ctx context.Context, kv ...interface{}) context.Context {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	parent, _ := ctx.Value(ctxFieldsKey{}).([]field)
+	fields := make([]field, 0, len(parent)+(len(kv)+1)/2)
+	fields = append(fields, parent...)
+	fields = appendFields(fields, kv...)
+	return context.WithValue(ctx, ctxFieldsKey{}, fields)
+}
+
+// RegisterContextExtractor 注册context字段提取函数，用于自动提取保存在context中的请求ID、链路ID等信息
+func RegisterContextExtractor(fn ContextExtractor) {
+	if fn == nil {
+		return
+	}
+	extractorMu.Lock()
+	defer extractorMu.Unlock()
+	contextExtractors = append(contextExtractors, fn)
+}
+
+// contextFields 获取context中的全部日志字段
+func contextFields(ctx context.Context) []field {
+	if ctx == nil {
+		return nil
+	}
+	fields, _ := ctx.Value(ctxFieldsKey{}).([]field)
+	extractorMu.RLock()
+	defer extractorMu.RUnlock()
+	if len(contextExtractors) == 0 {
+		return fields
+	}
+	// 限制容量，避免修改context中保存的字段
+	fields = fields[:len(fields):len(fields)]
+	for _, fn := range contextExtractors {
+		fields = appendFields(fields, fn(ctx)...)
+	}
+	return fields
+}
+
+// WithContext 返回一个附加了context中日志字段的子日志对象
+func (l *StdLogger) WithContext(ctx context.Context) *StdLogger {
+	fields := contextFields(ctx)
+	if len(fields) == 0 {
+		return l
+	}
+	return &StdLogger{
+		loggerCore: l.loggerCore,
+		fields:     append(l.fields[:len(l.fields):len(l.fields)], fields...),
+	}
+}
+
+// ctxLog 记录附加了context中日志字段的日志，calldepth为调用者相对于本方法的栈深度
+func (l *StdLogger) ctxLog(ctx context.Context, calldepth int, level string, mode int, format string, v []interface{}) {
+	vlevel, ok := l.check(calldepth+1, level)
+	if !ok {
+		return
+	}
+	l.WithContext(ctx).logDepth(calldepth+1, level, vlevel, sprint(mode, format, v))
+}
+
+func (l *StdLogger) LogContext(ctx context.Context, level string, v ...interface{}) {
+	l.ctxLog(ctx, 2, level, printMode, "", v)
+}
+
+func (l *StdLogger) LogfContext(ctx context.Context, level string, format string, v ...interface{}) {
+	l.ctxLog(ctx, 2, level, printfMode, format, v)
+}
+
+func (l *StdLogger) TraceContext(ctx context.Context, v ...interface{}) {
+	l.ctxLog(ctx, 2, def.LogLevelTrace, printMode, "", v)
+}
+
+func (l *StdLogger) TracefContext(ctx context.Context, format string, v ...interface{}) {
+	l.ctxLog(ctx, 2, def.LogLevelTrace, printfMode, format, v)
+}
+
+func (l *StdLogger) DebugContext(ctx context.Context, v ...interface{}) {
+	l.ctxLog(ctx, 2, def.LogLevelDebug, printMode, "", v)
+}
+
+func (l *StdLogger) DebugfContext(ctx context.Context, format string, v ...interface{}) {
+	l.ctxLog(ctx, 2, def.LogLevelDebug, printfMode, format, v)
+}
+
+func (l *StdLogger) InfoContext(ctx context.Context, v ...interface{}) {
+	l.ctxLog(ctx, 2, def.LogLevelInfo, printMode, "", v)
+}
+
+func (l *StdLogger) InfofContext(ctx context.Context, format string, v ...interface{}) {
+	l.ctxLog(ctx, 2, def.LogLevelInfo, printfMode, format, v)
+}
+
+func (l *StdLogger) WarnContext(ctx context.Context, v ...interface{}) {
+	l.ctxLog(ctx, 2, def.LogLevelWarn, printMode, "", v)
+}
+
+func (l *StdLogger) WarnfContext(ctx context.Context, format string, v ...interface{}) {
+	l.ctxLog(ctx, 2, def.LogLevelWarn, printfMode, format, v)
+}
+
+func (l *StdLogger) ErrorContext(ctx context.Context, v ...interface{}) {
+	l.ctxLog(ctx, 2, def.LogLevelError, printMode, "", v)
+}
+
+func (l *StdLogger) ErrorfContext(ctx context.Context, format string, v ...interface{}) {
+	l.ctxLog(ctx, 2, def.LogLevelError, printfMode, format, v)
+}
+
+// LogContext record a specified level's log with fields in context
+func LogContext(ctx context.Context, level string, v ...interface{}) {
+	Use("default").ctxLog(ctx, 2, level, printMode, "", v)
+}
+
+// LogfContext record a specified level's formatted log with fields in context
+func LogfContext(ctx context.Context, level string, format string, v ...interface{}) {
+	Use("default").ctxLog(ctx, 2, level, printfMode, format, v)
+}
+
+func TraceContext(ctx context.Context, v ...interface{}) {
+	Use("default").ctxLog(ctx, 2, def.LogLevelTrace, printMode, "", v)
+}
+
+func TracefContext(ctx context.Context, format string, v ...interface{}) {
+	Use("default").ctxLog(ctx, 2, def.LogLevelTrace, printfMode, format, v)
+}
+
+func DebugContext(ctx context.Context, v ...interface{}) {
+	Use("default").ctxLog(ctx, 2, def.LogLevelDebug, printMode, "", v)
+}
+
+func DebugfContext(ctx context.Context, format string, v ...interface{}) {
+	Use("default").ctxLog(ctx, 2, def.LogLevelDebug, printfMode, format, v)
+}
+
+func InfoContext(ctx context.Context, v ...interface{}) {
+	Use("default").ctxLog(ctx, 2, def.LogLevelInfo, printMode, "", v)
+}
+
+func InfofContext(ctx context.Context, format string, v ...interface{}) {
+	Use("default").ctxLog(ctx, 2, def.LogLevelInfo, printfMode, format, v)
+}
+
+func WarnContext(ctx context.Context, v ...interface{}) {
+	Use("default").ctxLog(ctx, 2, def.LogLevelWarn, printMode, "", v)
+}
+
+func WarnfContext(ctx context.Context, format string, v ...interface{}) {
+	Use("default").ctxLog(ctx, 2, def.LogLevelWarn, printfMode, format, v)
+}
+
+func ErrorContext(ctx context.Context, v ...interface{}) {
+	Use("default").ctxLog(ctx, 2, def.LogLevelError, printMode, "", v)
+}
+
+func ErrorfContext(ctx context.Context, format string, v ...interface{}) {
+	Use("default").ctxLog(ctx, 2, def.LogLevelError, printfMode, format, v)
+}
@@ -2,7 +2,8 @@ package def
 
 // 定义日志等级
 const (
-	LevelDebug = iota
+	LevelTrace = iota - 1
+	LevelDebug
 	LevelInfo
 	LevelWarn
 	LevelError
@@ -14,6 +15,7 @@ const (
 	LogToStdout = iota // 输出到标准输出
 	LogToStderr        // 输出到标准错误输出
 	LogToFile          // 输出到文件
+	LogToSink          // 输出到自定义的Sink
 )
 
 // 定义日志切割类型
@@ -25,6 +27,28 @@ const (
 	RotateByHour
 )
 
+// 定义日志输出格式
+const (
+	FormatText = iota // 文本格式
+	FormatJson        // JSON格式，每条日志一行
+	FormatLogfmt      // logfmt格式，如：ts=... level=info msg="..."
+)
+
+// 定义日志文件压缩方式
+const (
+	CompressNone = iota
+	CompressGzip
+	CompressZstd
+)
+
+// 定义异步模式下队列已满时的处理方式
+const (
+	OverflowBlock      = iota // 阻塞等待
+	OverflowDropNewest        // 丢弃新的日志
+	OverflowDropOldest        // 丢弃队列中最早的日志
+	OverflowDropBelow         // 丢弃低于指定等级的日志，其他日志阻塞等待
+)
+
 // flags
 const (
 	Ldate         = 1 << iota     // the date in the local time zone: 2009/01/23
@@ -37,6 +61,7 @@ const (
 )
 
 // 日志级别字符串
+const LogLevelTrace = "trace"
 const LogLevelDebug = "debug"
 const LogLevelInfo = "info"
 const LogLevelWarn = "warn"
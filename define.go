@@ -11,29 +11,79 @@ import (
 
 // Config 定义日志配置
 type Config struct {
-    LogPath       string `json:"log_path" toml:"log_path" yaml:"log_path"`                      // 定义日志根路径
-    LogPrefix     string `json:"log_prefix" toml:"log_prefix" yaml:"log_prefix"`                // 日志文件前缀
-    Output        string `json:"output" toml:"output" yaml:"output"`                            // 日志输出类型,file,stdout,stderr
-    LogLevel      string `json:"log_level" toml:"log_level" yaml:"log_level"`                   // 日志等级，可取值:debug,info,warn,error,fatal
-    Rotate        string `json:"rotate" toml:"rotate" yaml:"rotate"`                            // 日志切割类型,可取值：none,year,month,date,hour
-    LogStackLevel string `json:"log_stack_level" toml:"log_stack_level" yaml:"log_stack_level"` // 记录调用栈信息的日志等级
-    ColorfulPrint bool   `json:"colorful_print" toml:"colorful_print" yaml:"colorful_print"`    // 是否开启彩色打印，仅适用于标准输出，不适用于文件输出
-    Switch        string `json:"switch" toml:"switch" yaml:"switch"`                            // 开关，off-关闭，on/empty-开启
+    LogPath       string          `json:"log_path" toml:"log_path" yaml:"log_path"`                      // 定义日志根路径
+    LogPrefix     string          `json:"log_prefix" toml:"log_prefix" yaml:"log_prefix"`                // 日志文件前缀
+    Output        string          `json:"output" toml:"output" yaml:"output"`                            // 日志输出类型,file,stdout,stderr
+    LogLevel      string          `json:"log_level" toml:"log_level" yaml:"log_level"`                   // 日志等级，可取值:trace,debug,info,warn,error,fatal以及自定义的日志等级
+    VModule       string          `json:"vmodule" toml:"vmodule" yaml:"vmodule"`                         // 按调用位置设置日志等级，如 payment/*=debug,order.go=trace，匹配时代替LogLevel，Outputs中的日志等级仍然有效
+    Rotate        string          `json:"rotate" toml:"rotate" yaml:"rotate"`                            // 日志切割类型,可取值：none,year,month,date,hour
+    LogStackLevel string          `json:"log_stack_level" toml:"log_stack_level" yaml:"log_stack_level"` // 记录调用栈信息的日志等级
+    ColorfulPrint bool            `json:"colorful_print" toml:"colorful_print" yaml:"colorful_print"`    // 是否开启彩色打印，仅适用于标准输出，不适用于文件输出
+    Switch        string          `json:"switch" toml:"switch" yaml:"switch"`                            // 开关，off-关闭，on/empty-开启
+    MaxSize       string          `json:"max_size" toml:"max_size" yaml:"max_size"`                      // 单个日志文件最大尺寸，如100MB，超过后切割为.1.log、.2.log...，空表示不限制
+    MaxAge        string          `json:"max_age" toml:"max_age" yaml:"max_age"`                         // 切割后的日志文件最长保留时间，如7d、72h，空表示不限制
+    MaxBackups    int             `json:"max_backups" toml:"max_backups" yaml:"max_backups"`             // 切割后的日志文件最多保留个数，0表示不限制
+    Compress      string          `json:"compress" toml:"compress" yaml:"compress"`                      // 切割后的日志文件压缩方式，可取值：gzip,zstd，空表示不压缩
+    WatchFile     bool            `json:"watch_file" toml:"watch_file" yaml:"watch_file"`                // 是否检查日志文件被删除或者移动（如被logrotate处理），是则重新打开文件
+    Async         bool            `json:"async" toml:"async" yaml:"async"`                               // 是否开启异步模式，日志先写入队列，由后台协程写入输出目标
+    QueueSize     int             `json:"queue_size" toml:"queue_size" yaml:"queue_size"`                // 异步模式的队列长度，默认1024
+    Overflow      string          `json:"overflow" toml:"overflow" yaml:"overflow"`                      // 异步队列已满时的处理方式，可取值：block,drop_newest,drop_oldest,drop_below，默认为block
+    OverflowLevel string          `json:"overflow_level" toml:"overflow_level" yaml:"overflow_level"`    // 队列已满时丢弃低于此等级的日志，仅当Overflow为drop_below时有效
+    Sink          Sink            `json:"-" toml:"-" yaml:"-"`                                           // 自定义输出目标，设置后忽略Output
+    Format        string          `json:"format" toml:"format" yaml:"format"`                            // 日志格式，可取值：text,json,logfmt，默认为text
+    Flags         string          `json:"flags" toml:"flags" yaml:"flags"`                               // 日志格式标签，如date,time,microseconds,shortfile，默认为date,time,microseconds,shortfile
+    TimeFormat    string          `json:"time_format" toml:"time_format" yaml:"time_format"`             // 时间格式，可以是time包中的格式名称（如RFC3339Nano）或者自定义格式，默认文本格式为2006/01/02 15:04:05.000000
+    TimeZone      string          `json:"time_zone" toml:"time_zone" yaml:"time_zone"`                   // 时区，如UTC、Local、Asia/Shanghai，默认为本地时区
+    Outputs       []*OutputConfig `json:"outputs" toml:"outputs" yaml:"outputs"`                         // 多个输出目标，设置后忽略Output以及Sink
+    Reset         []string        `json:"reset" toml:"reset" yaml:"reset"`                               // 不继承上级日志对象、重置为零值的选项，使用配置文件中的名称，如colorful_print、async、max_backups
+}
+
+// OutputConfig 定义一个输出目标，用于将同一个日志对象的日志以不同的等级和方式输出到多个地方
+// 未设置的文件相关选项使用所属Config中的设置
+type OutputConfig struct {
+    Output        string `json:"output" toml:"output" yaml:"output"`                         // 日志输出类型,file,stdout,stderr
+    LogLevel      string `json:"log_level" toml:"log_level" yaml:"log_level"`                // 输出的最低日志等级，为空时使用Config中的设置
+    ColorfulPrint bool   `json:"colorful_print" toml:"colorful_print" yaml:"colorful_print"` // 是否开启彩色打印，仅适用于标准输出，不适用于文件输出
+    Format        string `json:"format" toml:"format" yaml:"format"`                         // 日志格式，为空时使用Config中的设置
+    LogPath       string `json:"log_path" toml:"log_path" yaml:"log_path"`                   // 日志根路径
+    LogPrefix     string `json:"log_prefix" toml:"log_prefix" yaml:"log_prefix"`             // 日志文件前缀
+    Rotate        string `json:"rotate" toml:"rotate" yaml:"rotate"`                         // 日志切割类型
+    MaxSize       string `json:"max_size" toml:"max_size" yaml:"max_size"`                   // 单个日志文件最大尺寸
+    MaxAge        string `json:"max_age" toml:"max_age" yaml:"max_age"`                      // 切割后的日志文件最长保留时间
+    MaxBackups    int    `json:"max_backups" toml:"max_backups" yaml:"max_backups"`          // 切割后的日志文件最多保留个数
+    Compress      string `json:"compress" toml:"compress" yaml:"compress"`                   // 切割后的日志文件压缩方式
+    WatchFile     bool   `json:"watch_file" toml:"watch_file" yaml:"watch_file"`             // 是否检查日志文件被删除或者移动
+    Sink          Sink   `json:"-" toml:"-" yaml:"-"`                                        // 自定义输出目标，设置后忽略Output
 }
 
 // LogDefinition 日志定义，由Config转换后得到
 type LogDefinition struct {
-    Dir           string   // 定义日志存储目录，默认存储在当前目录下的logs目录
-    FilePrefix    string   // 定义日志文件名前缀
-    OutputType    int      // 定义日志输出类型
-    Output        *os.File // 定义日志输出目标
-    RotateType    int      // 定义日志切割类型
-    Level         int      // 设置日志记录级别
-    Flags         int      // 日志格式标签
-    LogStack      bool     // 是否记录日志调用栈信息
-    LogStackLevel int      // 记录调用栈的日志等级
-    ColorfulPrint bool     // 是否开启彩色打印，仅适用于标准输出，不适用于文件输出
-    Disabled      bool     // 是否禁用
+    Dir           string           // 定义日志存储目录，默认存储在当前目录下的logs目录
+    FilePrefix    string           // 定义日志文件名前缀
+    OutputType    int              // 定义日志输出类型
+    Output        *os.File         // 定义日志输出目标
+    Sink          Sink             // 自定义输出目标，仅当输出类型为 LogToSink 有效
+    RotateType    int              // 定义日志切割类型
+    MaxSize       int64            // 单个日志文件最大字节数，0表示不限制
+    MaxAge        time.Duration    // 切割后的日志文件最长保留时间，0表示不限制
+    MaxBackups    int              // 切割后的日志文件最多保留个数，0表示不限制
+    Compress      int              // 切割后的日志文件压缩方式
+    Level         int              // 设置日志记录级别
+    VModule       string           // 按调用位置设置的日志等级规则
+    Flags         int              // 日志格式标签
+    Format        int              // 日志输出格式
+    TimeFormat    string           // 时间格式，为空时使用默认格式
+    Location      *time.Location   // 时区，为空时使用本地时区或者根据LUTC使用UTC时间
+    LogStack      bool             // 是否记录日志调用栈信息
+    LogStackLevel int              // 记录调用栈的日志等级
+    ColorfulPrint bool             // 是否开启彩色打印，仅适用于标准输出，不适用于文件输出
+    Disabled      bool             // 是否禁用
+    WatchFile     bool             // 是否检查日志文件被删除或者移动
+    Async         bool             // 是否开启异步模式
+    QueueSize     int              // 异步模式的队列长度
+    Overflow      int              // 异步队列已满时的处理方式
+    OverflowLevel int              // 队列已满时丢弃低于此等级的日志
+    Outputs       []*LogDefinition // 多个输出目标的定义
 }
 
 // 返回一个默认的日志定义
@@ -51,38 +101,77 @@ func DefaultConfig() *Config {
     return c
 }
 
-// 返回一个默认的日志定义
-func defaultLogDefinition() *LogDefinition {
-    d := &LogDefinition{}
-    d.Dir = LogDir
-    d.Level = logLevel
-    d.FilePrefix = LogFilePrefix
-    d.Flags = logFlags
-    d.Output = logOutput
-    d.OutputType = logOutputType
-    d.RotateType = logRotateType
-    d.LogStack = logStack
-    d.LogStackLevel = logStackLevel
-    d.ColorfulPrint = colorfulPrint
-    d.Disabled = false
-    return d
+// clone 返回日志定义的副本，包括各个输出目标的定义
+func (d *LogDefinition) clone() *LogDefinition {
+    c := *d
+    if len(d.Outputs) > 0 {
+        c.Outputs = make([]*LogDefinition, 0, len(d.Outputs))
+        for _, od := range d.Outputs {
+            c.Outputs = append(c.Outputs, od.clone())
+        }
+    }
+    return &c
+}
+
+// outputConfig 将输出目标的设置合并到日志配置中，得到该输出目标的完整配置
+func (c *Config) outputConfig(o *OutputConfig) *Config {
+    oc := *c
+    oc.Outputs = nil
+    oc.Output = o.Output
+    oc.Sink = o.Sink
+    oc.ColorfulPrint = o.ColorfulPrint
+    if o.LogLevel != "" {
+        oc.LogLevel = o.LogLevel
+    }
+    if o.Format != "" {
+        oc.Format = o.Format
+    }
+    if o.LogPath != "" {
+        oc.LogPath = o.LogPath
+    }
+    if o.LogPrefix != "" {
+        oc.LogPrefix = o.LogPrefix
+    }
+    if o.Rotate != "" {
+        oc.Rotate = o.Rotate
+    }
+    if o.MaxSize != "" {
+        oc.MaxSize = o.MaxSize
+    }
+    if o.MaxAge != "" {
+        oc.MaxAge = o.MaxAge
+    }
+    if o.MaxBackups > 0 {
+        oc.MaxBackups = o.MaxBackups
+    }
+    if o.Compress != "" {
+        oc.Compress = o.Compress
+    }
+    if o.WatchFile {
+        oc.WatchFile = true
+    }
+    return &oc
 }
 
 // 根据配置返回一个日志定义
 func newLogDefinition(cfg *Config) *LogDefinition {
-    // 没有配置则忽略
+    // 没有配置则使用默认注册表的默认设置
     if cfg == nil {
-        return defaultLogDefinition()
+        return std.defaultLogDefinition()
     }
     d := &LogDefinition{}
     // 设置日志输出类型
     // LogToFile - 输出到文件
     // LogToStdout - 输出到标准输出
     // LogToStderr - 输出到标准错误输出
-    switch cfg.Output {
-    case "file":
+    // LogToSink - 输出到自定义的Sink
+    switch {
+    case cfg.Sink != nil:
+        d.OutputType = def.LogToSink
+        d.Sink = cfg.Sink
+    case cfg.Output == "file":
         d.OutputType = def.LogToFile
-    case "stderr":
+    case cfg.Output == "stderr":
         d.OutputType = def.LogToStderr
     default:
         // 不设置默认全部输出到标准输出设备
@@ -90,6 +179,8 @@ func newLogDefinition(cfg *Config) *LogDefinition {
     }
     // 设置日志等级
     switch cfg.LogLevel {
+    case "trace":
+        d.Level = def.LevelTrace
     case "debug":
         d.Level = def.LevelDebug
     case "info":
@@ -101,11 +192,34 @@ func newLogDefinition(cfg *Config) *LogDefinition {
     case "fatal":
         d.Level = def.LevelFatal
     default:
-        d.Level = def.LevelError
+        // 自定义的日志等级，未注册时为error
+        d.Level = util.NumLogLevel(cfg.LogLevel)
     }
-    // 设置flag，此处的内容与golang中的log包的相关设置相同
-    // 此处暂不支持自定义设置，如果需要设置需要在此方法之外（前）自行设定
+    // 按调用位置设置的日志等级
+    d.VModule = cfg.VModule
+    // 设置flag，此处的内容与golang中的log包的相关设置相同，未设置或者无法解析时使用默认值
     d.Flags = def.Ldate | def.Ltime | def.Lmicroseconds | def.Lshortfile
+    if cfg.Flags != "" {
+        if flags, err := util.ParseFlags(cfg.Flags); err == nil {
+            d.Flags = flags
+        }
+    }
+    // 设置时间格式以及时区，时区无法识别时使用本地时区
+    d.TimeFormat = util.TimeLayout(cfg.TimeFormat)
+    if cfg.TimeZone != "" {
+        if loc, err := time.LoadLocation(cfg.TimeZone); err == nil {
+            d.Location = loc
+        }
+    }
+    // 设置日志输出格式
+    switch cfg.Format {
+    case "json":
+        d.Format = def.FormatJson
+    case "logfmt":
+        d.Format = def.FormatLogfmt
+    default:
+        d.Format = def.FormatText
+    }
     // 设置日志文件存储目录，仅当输出类型为 LogToFile 有效
     _, _ = util.InitLogDir(cfg.LogPath)
     d.Dir = cfg.LogPath
@@ -125,6 +239,23 @@ func newLogDefinition(cfg *Config) *LogDefinition {
         d.RotateType = def.RotateByDate
     }
 
+    // 设置日志文件最大尺寸，无法解析时不限制
+    d.MaxSize, _ = util.ParseSize(cfg.MaxSize)
+
+    // 设置日志文件保留策略，无法解析时不清理
+    d.MaxAge, _ = util.ParseDuration(cfg.MaxAge)
+    d.MaxBackups = cfg.MaxBackups
+
+    // 设置日志文件压缩方式
+    switch cfg.Compress {
+    case "gzip":
+        d.Compress = def.CompressGzip
+    case "zstd":
+        d.Compress = def.CompressZstd
+    default:
+        d.Compress = def.CompressNone
+    }
+
     // 设置日志文件名前缀，仅当输出类型为 LogToFile 有效
     d.FilePrefix = cfg.LogPrefix
 
@@ -145,6 +276,39 @@ func newLogDefinition(cfg *Config) *LogDefinition {
         d.Disabled = false
     }
 
+    // 检查日志文件是否被删除或者移动
+    d.WatchFile = cfg.WatchFile
+
+    // 异步模式
+    d.Async = cfg.Async
+    d.QueueSize = cfg.QueueSize
+    switch cfg.Overflow {
+    case "drop_newest":
+        d.Overflow = def.OverflowDropNewest
+    case "drop_oldest":
+        d.Overflow = def.OverflowDropOldest
+    case "drop_below":
+        d.Overflow = def.OverflowDropBelow
+    default:
+        d.Overflow = def.OverflowBlock
+    }
+    d.OverflowLevel = util.NumLogLevel(cfg.OverflowLevel)
+
+    // 多个输出目标，日志等级取各个输出目标中最低的等级
+    if len(cfg.Outputs) > 0 {
+        d.Outputs = make([]*LogDefinition, 0, len(cfg.Outputs))
+        for _, o := range cfg.Outputs {
+            if o == nil {
+                continue
+            }
+            od := newLogDefinition(cfg.outputConfig(o))
+            if len(d.Outputs) == 0 || od.Level < d.Level {
+                d.Level = od.Level
+            }
+            d.Outputs = append(d.Outputs, od)
+        }
+    }
+
     return d
 }
 
@@ -167,8 +331,20 @@ func (d *LogDefinition) GetLogRotateTimeFmt() string {
 // getLogFilePath 获取日志文件路径
 func (d *LogDefinition) GetLogFilePath() string {
     if d.RotateType == def.RotateNone {
-        return fmt.Sprintf("%s/%s%s.log", d.Dir, d.FilePrefix, "all")
+        return d.getLogFilePath("", 0)
     }
     logRotateTimeFmt := util.GetLogRotateTimeFmt(d.RotateType)
-    return fmt.Sprintf("%s/%s%s.log", d.Dir, d.FilePrefix, time.Now().Format(logRotateTimeFmt))
+    return d.getLogFilePath(time.Now().Format(logRotateTimeFmt), 0)
+}
+
+// getLogFilePath 根据时间标记以及按尺寸切割的序号获取日志文件路径
+// 序号为0时返回 prefix20261017.log，否则返回 prefix20261017.1.log 的形式
+func (d *LogDefinition) getLogFilePath(mark string, idx int) string {
+    if mark == "" {
+        mark = "all"
+    }
+    if idx <= 0 {
+        return fmt.Sprintf("%s/%s%s.log", d.Dir, d.FilePrefix, mark)
+    }
+    return fmt.Sprintf("%s/%s%s.%d.log", d.Dir, d.FilePrefix, mark, idx)
 }
@@ -0,0 +1,85 @@
+package xlog
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"sync/atomic"
+)
+
+// ErrUnknownLogger 严格模式下使用未注册的日志对象时报告的错误
+var ErrUnknownLogger = errors.New("xlog: unknown logger")
+
+// ErrUnknownLevel 使用未注册的日志等级记录日志时报告的错误，日志按照error等级记录
+var ErrUnknownLevel = errors.New("xlog: unknown level")
+
+// SetStrict 设置默认注册表是否开启严格模式
+// 开启后Use使用未注册的日志对象（且没有已注册的上级日志对象）时，通过诊断回调报告一次，用于发现名称拼写错误等配置问题
+// 报告后仍然使用默认日志对象
+func SetStrict(enabled bool) {
+	std.SetStrict(enabled)
+}
+
+// SetDiagnosticHook 设置默认注册表的诊断回调，fn为nil时输出到标准错误输出
+// 报告的错误可以通过errors.Is(err, ErrUnknownLogger)判断
+func SetDiagnosticHook(fn func(err error)) {
+	std.SetDiagnosticHook(fn)
+}
+
+// SetStrict 设置是否开启严格模式，参考包级别的SetStrict
+func (r *Registry) SetStrict(enabled bool) {
+	var v int32
+	if enabled {
+		v = 1
+	}
+	atomic.StoreInt32(&r.strict, v)
+}
+
+// SetDiagnosticHook 设置诊断回调，fn为nil时输出到标准错误输出
+func (r *Registry) SetDiagnosticHook(fn func(err error)) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.diagnostic = fn
+}
+
+// strictMode 是否开启严格模式
+func (r *Registry) strictMode() bool {
+	return atomic.LoadInt32(&r.strict) == 1
+}
+
+// reportUnknown 报告使用了未注册的日志对象，同一个名称只报告一次
+func (r *Registry) reportUnknown(name string) {
+	if _, loaded := r.reported.LoadOrStore(name, struct{}{}); loaded {
+		return
+	}
+	r.diagnose(fmt.Errorf("%w %q, using default logger", ErrUnknownLogger, name))
+}
+
+// reportUnknownLevel 通过日志对象所属注册表的诊断回调报告使用了未注册的日志等级，不受严格模式影响
+func (l *StdLogger) reportUnknownLevel(level string) {
+	r := l.registry
+	if r == nil {
+		r = std
+	}
+	r.reportUnknownLevel(level)
+}
+
+// reportUnknownLevel 报告使用了未注册的日志等级，同一个名称只报告一次
+func (r *Registry) reportUnknownLevel(level string) {
+	if _, loaded := r.reportedLevels.LoadOrStore(level, struct{}{}); loaded {
+		return
+	}
+	r.diagnose(fmt.Errorf("%w %q, logged as error", ErrUnknownLevel, level))
+}
+
+// diagnose 通过诊断回调报告配置问题
+func (r *Registry) diagnose(err error) {
+	r.mu.RLock()
+	fn := r.diagnostic
+	r.mu.RUnlock()
+	if fn != nil {
+		fn(err)
+		return
+	}
+	fmt.Fprintln(os.Stderr, err)
+}
@@ -0,0 +1,164 @@
+package xlog
+
+import (
+	"bytes"
+	"strings"
+	"time"
+
+	"github.com/whencome/xlog/def"
+	"github.com/whencome/xlog/util"
+)
+
+// logEntry 一条待输出的日志
+type logEntry struct {
+	time   time.Time
+	level  string
+	file   string
+	line   int
+	msg    string
+	stack  string
+	logger string
+	fields []field
+}
+
+// encodeEntry 按照输出目标定义的格式将日志追加到buf中
+func encodeEntry(buf *[]byte, o *loggerOutput, e *logEntry) {
+	switch o.def.Format {
+	case def.FormatJson:
+		encodeJson(buf, o.def, e)
+	case def.FormatLogfmt:
+		encodeLogfmt(buf, o.def, e)
+	default:
+		encodeText(buf, o.def, e, o.colorful())
+		if e.stack != "" {
+			// 调用栈信息单独作为一条日志输出
+			stackEntry := *e
+			stackEntry.msg = e.stack
+			stackEntry.stack = ""
+			stackEntry.fields = nil
+			encodeText(buf, o.def, &stackEntry, o.colorful())
+		}
+	}
+}
+
+// encodeText 文本格式，如：2026/10/17 10:00:00 [INFO] file.go:12: msg
+func encodeText(buf *[]byte, d *LogDefinition, e *logEntry, colorful bool) {
+	// colorful print begin
+	if colorful {
+		if c := util.LevelColor(e.level); c > 0 {
+			*buf = append(*buf, "\x1b["...)
+			util.Itoa(buf, c, -1)
+			*buf = append(*buf, 'm')
+		}
+	}
+	// log prefix
+	util.FormatLogPrefixLayout(buf, d.Flags, d.TimeFormat, d.Location, e.time, e.level, e.file, e.line)
+	// log content
+	*buf = append(*buf, e.msg...)
+	// 附加字段，以key=value的形式追加在日志内容之后
+	if len(e.fields) > 0 {
+		*buf = bytes.TrimSuffix(*buf, []byte{'\n'})
+		appendLogfmtFields(buf, e.fields)
+	}
+	if len(*buf) == 0 || (*buf)[len(*buf)-1] != '\n' {
+		*buf = append(*buf, '\n')
+	}
+	// colorful print end
+	if colorful {
+		*buf = append(*buf, "\x1b[0m"...)
+	}
+}
+
+// encodeJson JSON格式，每条日志输出为一行JSON对象
+// 如：{"time":"2026-10-17T10:00:00.000000+08:00","level":"info","caller":"file.go:12","msg":"msg","logger":"order"}
+func encodeJson(buf *[]byte, d *LogDefinition, e *logEntry) {
+	*buf = append(*buf, `{"time":`...)
+	util.AppendJsonString(buf, string(appendTime(nil, d, e.time)))
+	*buf = append(*buf, `,"level":`...)
+	util.AppendJsonString(buf, e.level)
+	if e.file != "" {
+		*buf = append(*buf, `,"caller":`...)
+		util.AppendJsonString(buf, callerString(d.Flags, e.file, e.line))
+	}
+	*buf = append(*buf, `,"msg":`...)
+	util.AppendJsonString(buf, strings.TrimSuffix(e.msg, "\n"))
+	if e.logger != "" {
+		*buf = append(*buf, `,"logger":`...)
+		util.AppendJsonString(buf, e.logger)
+	}
+	for _, f := range e.fields {
+		*buf = append(*buf, ',')
+		util.AppendJsonString(buf, f.key)
+		*buf = append(*buf, ':')
+		util.AppendJsonValue(buf, f.value)
+	}
+	if e.stack != "" {
+		*buf = append(*buf, `,"stack":`...)
+		util.AppendJsonString(buf, e.stack)
+	}
+	*buf = append(*buf, "}\n"...)
+}
+
+// encodeLogfmt logfmt格式，每条日志输出为一行
+// 如：ts=2026-10-17T10:00:00.000000+08:00 level=info caller=file.go:12 msg="some message" logger=order
+func encodeLogfmt(buf *[]byte, d *LogDefinition, e *logEntry) {
+	*buf = append(*buf, "ts="...)
+	util.AppendLogfmtValue(buf, string(appendTime(nil, d, e.time)))
+	*buf = append(*buf, " level="...)
+	util.AppendLogfmtValue(buf, e.level)
+	if e.file != "" {
+		*buf = append(*buf, " caller="...)
+		util.AppendLogfmtValue(buf, callerString(d.Flags, e.file, e.line))
+	}
+	*buf = append(*buf, " msg="...)
+	util.AppendLogfmtValue(buf, strings.TrimSuffix(e.msg, "\n"))
+	if e.logger != "" {
+		*buf = append(*buf, " logger="...)
+		util.AppendLogfmtValue(buf, e.logger)
+	}
+	appendLogfmtFields(buf, e.fields)
+	if e.stack != "" {
+		*buf = append(*buf, " stack="...)
+		util.AppendLogfmtValue(buf, e.stack)
+	}
+	*buf = append(*buf, '\n')
+}
+
+// appendLogfmtFields 以 key=value 的形式追加字段，每个字段前有一个空格
+func appendLogfmtFields(buf *[]byte, fields []field) {
+	for _, f := range fields {
+		*buf = append(*buf, ' ')
+		util.AppendLogfmtKey(buf, f.key)
+		*buf = append(*buf, '=')
+		util.AppendLogfmtValue(buf, util.FormatValue(f.value))
+	}
+}
+
+// 结构化日志的默认时间格式
+const structuredTimeLayout = "2006-01-02T15:04:05.000000Z07:00"
+
+// appendTime 按照日志定义的时间格式以及时区追加时间，用于结构化日志
+func appendTime(buf []byte, d *LogDefinition, t time.Time) []byte {
+	if d.Location != nil {
+		t = t.In(d.Location)
+	} else if d.Flags&def.LUTC != 0 {
+		t = t.UTC()
+	}
+	layout := d.TimeFormat
+	if layout == "" {
+		layout = structuredTimeLayout
+	}
+	return t.AppendFormat(buf, layout)
+}
+
+// callerString 返回调用位置，如 file.go:12
+func callerString(flags int, file string, line int) string {
+	if flags&def.Lshortfile != 0 {
+		file = util.ShortFile(file)
+	}
+	buf := make([]byte, 0, len(file)+8)
+	buf = append(buf, file...)
+	buf = append(buf, ':')
+	util.Itoa(&buf, line, -1)
+	return string(buf)
+}
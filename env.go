@@ -0,0 +1,224 @@
+package xlog
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/whencome/xlog/util"
+)
+
+// envField 可以通过环境变量设置的配置项
+type envField struct {
+	name string                          // 环境变量名称，不包括前缀以及日志对象名称
+	set  func(c *Config, v string) error // 校验并设置配置项
+}
+
+// EnvPrefix InitFromFile以及WatchConfigFile加载配置文件后使用的环境变量前缀
+const EnvPrefix = "XLOG"
+
+// 可以通过环境变量设置的配置项，如前缀为XLOG时，XLOG_LEVEL对应LogLevel
+var envFields = []envField{
+	{"PATH", func(c *Config, v string) error { c.LogPath = v; return nil }},
+	{"PREFIX", func(c *Config, v string) error { c.LogPrefix = v; return nil }},
+	{"OUTPUT", func(c *Config, v string) error { return setEnum(&c.Output, v, outputNames) }},
+	{"LEVEL", func(c *Config, v string) error { return setLevel(&c.LogLevel, v, false) }},
+	{"VMODULE", func(c *Config, v string) error {
+		if _, err := parseVModule(v); err != nil {
+			return err
+		}
+		c.VModule = v
+		return nil
+	}},
+	{"ROTATE", func(c *Config, v string) error { return setEnum(&c.Rotate, v, rotateNames) }},
+	{"STACK_LEVEL", func(c *Config, v string) error { return setLevel(&c.LogStackLevel, v, true) }},
+	{"COLORFUL", func(c *Config, v string) error { return setBool(&c.ColorfulPrint, v) }},
+	{"SWITCH", func(c *Config, v string) error { return setEnum(&c.Switch, v, switchNames) }},
+	{"FORMAT", func(c *Config, v string) error { return setEnum(&c.Format, v, formatNames) }},
+	{"FLAGS", func(c *Config, v string) error {
+		if _, err := util.ParseFlags(v); err != nil {
+			return err
+		}
+		c.Flags = v
+		return nil
+	}},
+	{"TIME_FORMAT", func(c *Config, v string) error { c.TimeFormat = v; return nil }},
+	{"TIME_ZONE", func(c *Config, v string) error {
+		if _, err := time.LoadLocation(v); err != nil {
+			return err
+		}
+		c.TimeZone = v
+		return nil
+	}},
+	{"MAX_SIZE", func(c *Config, v string) error {
+		if _, err := util.ParseSize(v); err != nil {
+			return err
+		}
+		c.MaxSize = v
+		return nil
+	}},
+	{"MAX_AGE", func(c *Config, v string) error {
+		if _, err := util.ParseDuration(v); err != nil {
+			return err
+		}
+		c.MaxAge = v
+		return nil
+	}},
+	{"MAX_BACKUPS", func(c *Config, v string) error { return setInt(&c.MaxBackups, v) }},
+	{"COMPRESS", func(c *Config, v string) error { return setEnum(&c.Compress, v, compressNames) }},
+	{"WATCH_FILE", func(c *Config, v string) error { return setBool(&c.WatchFile, v) }},
+	{"ASYNC", func(c *Config, v string) error { return setBool(&c.Async, v) }},
+	{"QUEUE_SIZE", func(c *Config, v string) error { return setInt(&c.QueueSize, v) }},
+	{"OVERFLOW", func(c *Config, v string) error { return setEnum(&c.Overflow, v, overflowNames) }},
+	{"OVERFLOW_LEVEL", func(c *Config, v string) error { return setLevel(&c.OverflowLevel, v, false) }},
+}
+
+// ConfigFromEnv 在默认配置（DefaultConfig）的基础上使用环境变量覆盖，得到日志配置
+// 如前缀为XLOG时，XLOG_LEVEL=info，XLOG_OUTPUT=file，XLOG_PATH=/home/logs等，支持的变量参考ApplyEnv
+func ConfigFromEnv(prefix string) (*Config, error) {
+	return ApplyEnv(DefaultConfig(), prefix, "")
+}
+
+// ApplyEnv 使用环境变量覆盖配置，返回覆盖后的新配置，不修改原配置，cfg为nil时在默认配置的基础上覆盖
+// 环境变量名称为 前缀_配置项 或者 前缀_日志对象名称_配置项，日志对象名称转换为大写，字母和数字以外的字符转换为下划线，
+// 如order对应XLOG_ORDER_LEVEL，order.payment对应XLOG_ORDER_PAYMENT_LEVEL
+// 名称可以有多种解释时按最长的配置项匹配，如XLOG_ORDER_STACK_LEVEL对应order的STACK_LEVEL，而不是order.stack的LEVEL；
+// 日志对象的环境变量与全局的环境变量同名时（如日志对象time的FORMAT与全局的TIME_FORMAT）返回错误
+// 优先级从高到低依次为：指定日志对象的环境变量（如XLOG_ORDER_LEVEL）、全局的环境变量（如XLOG_LEVEL）、代码或者配置文件中的配置
+// 配置项包括：PATH,PREFIX,OUTPUT,LEVEL,VMODULE,ROTATE,STACK_LEVEL,COLORFUL,SWITCH,FORMAT,FLAGS,TIME_FORMAT,TIME_ZONE,MAX_SIZE,MAX_AGE,MAX_BACKUPS,
+// COMPRESS,WATCH_FILE,ASYNC,QUEUE_SIZE,OVERFLOW,OVERFLOW_LEVEL，环境变量的值无效时返回错误
+func ApplyEnv(cfg *Config, prefix, name string) (*Config, error) {
+	if cfg == nil {
+		cfg = DefaultConfig()
+	}
+	c := *cfg
+	p := envName(prefix) + "_"
+	for _, f := range envFields {
+		if err := setEnv(&c, p+f.name, f); err != nil {
+			return nil, err
+		}
+	}
+	if name == "" {
+		return &c, nil
+	}
+	for _, f := range envFields {
+		key := p + envName(name) + "_" + f.name
+		if _, ok := os.LookupEnv(key); !ok {
+			continue
+		}
+		switch longestEnvField(key[len(p):]) {
+		case key[len(p):]:
+			return nil, fmt.Errorf("xlog: ambiguous environment variable %s: it is also a global variable and cannot be used for logger %q", key, name)
+		case f.name:
+			if err := setEnv(&c, key, f); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return &c, nil
+}
+
+// setEnv 环境变量存在时使用其值设置配置项，值无效时返回错误
+func setEnv(c *Config, key string, f envField) error {
+	v, ok := os.LookupEnv(key)
+	if !ok {
+		return nil
+	}
+	if err := f.set(c, strings.TrimSpace(v)); err != nil {
+		return fmt.Errorf("xlog: invalid environment variable %s=%q: %v", key, v, err)
+	}
+	return nil
+}
+
+// longestEnvField 返回与名称末尾匹配的最长的配置项，如ORDER_STACK_LEVEL返回STACK_LEVEL，没有匹配时返回空字符串
+func longestEnvField(s string) string {
+	longest := ""
+	for _, f := range envFields {
+		if len(f.name) > len(longest) && (s == f.name || strings.HasSuffix(s, "_"+f.name)) {
+			longest = f.name
+		}
+	}
+	return longest
+}
+
+// ApplyEnv 使用环境变量覆盖配置文件中的全部日志对象的配置，default对应的日志对象名称为default
+func (fc *FileConfig) ApplyEnv(prefix string) error {
+	if fc.Default != nil {
+		c, err := ApplyEnv(fc.Default, prefix, "default")
+		if err != nil {
+			return err
+		}
+		fc.Default = c
+	}
+	for k, cfg := range fc.Loggers {
+		if cfg == nil {
+			continue
+		}
+		c, err := ApplyEnv(cfg, prefix, k)
+		if err != nil {
+			return err
+		}
+		fc.Loggers[k] = c
+	}
+	return nil
+}
+
+// envName 将名称转换为环境变量名称，转换为大写，字母和数字以外的字符转换为下划线
+func envName(name string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z':
+			return r - 'a' + 'A'
+		case r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+			return r
+		}
+		return '_'
+	}, name)
+}
+
+// setEnum 校验值是否为允许的取值之一（不区分大小写），是则设置
+func setEnum(dst *string, v string, allowed []string) error {
+	v = strings.ToLower(v)
+	for _, a := range allowed {
+		if v == a {
+			*dst = v
+			return nil
+		}
+	}
+	return fmt.Errorf("must be one of %s", strings.Join(allowed, ","))
+}
+
+// setLevel 校验日志等级（内置等级不区分大小写），allowNone表示是否允许none
+func setLevel(dst *string, v string, allowNone bool) error {
+	if util.IsLogLevel(v) {
+		*dst = v
+		return nil
+	}
+	if lv := strings.ToLower(v); util.IsLogLevel(lv) || (allowNone && lv == "none") {
+		*dst = lv
+		return nil
+	}
+	return fmt.Errorf("not a registered level")
+}
+
+// setBool 解析并设置布尔值
+func setBool(dst *bool, v string) error {
+	b, err := strconv.ParseBool(v)
+	if err != nil {
+		return fmt.Errorf("must be a boolean")
+	}
+	*dst = b
+	return nil
+}
+
+// setInt 解析并设置非负整数
+func setInt(dst *int, v string) error {
+	n, err := strconv.Atoi(v)
+	if err != nil || n < 0 {
+		return fmt.Errorf("must be a non-negative integer")
+	}
+	*dst = n
+	return nil
+}
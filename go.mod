@@ -1,3 +1,9 @@
 module github.com/whencome/xlog
 
 go 1.14
+
+require (
+	github.com/BurntSushi/toml v1.2.1
+	github.com/klauspost/compress v1.13.6
+	gopkg.in/yaml.v3 v3.0.1
+)
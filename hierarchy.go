@@ -0,0 +1,122 @@
+package xlog
+
+import (
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// 日志对象按名称分级，使用.分隔，如 order、order.payment、order.payment.refund
+// 子日志对象的配置中未设置（零值）的选项继承最近的已注册上级日志对象的有效配置，需要关闭上级开启的选项时使用Config.Reset，
+// 未注册的日志对象在Use时使用最近的已注册上级日志对象
+// 子日志对象与上级写入同一个日志文件（或者同一个自定义输出目标）时共享上级的输出目标，不重复打开
+
+// parentName 返回上级日志对象的名称，没有上级时返回空字符串
+func parentName(name string) string {
+	if i := strings.LastIndexByte(name, '.'); i > 0 {
+		return name[:i]
+	}
+	return ""
+}
+
+// lookup 查找指定名称的日志对象，不存在时依次查找上级日志对象
+func (r *Registry) lookup(name string) (*StdLogger, bool) {
+	for k := name; k != ""; k = parentName(k) {
+		if l := r.MustUse(k); l != nil {
+			return l, true
+		}
+	}
+	return nil, false
+}
+
+// resolveConfig 计算日志对象的有效配置，即将配置中未设置的选项使用上级日志对象的有效配置补全
+// 同时返回最近的已注册上级日志对象，用于共享其输出目标，没有已注册的上级日志对象时返回原配置以及nil
+func (r *Registry) resolveConfig(name string, cfg *Config) (*Config, *StdLogger) {
+	for p := parentName(name); p != ""; p = parentName(p) {
+		pc, ok := r.configs.Load(p)
+		if !ok {
+			continue
+		}
+		resolved, _ := r.resolveConfig(p, pc.(*Config))
+		return cfg.inherit(resolved), r.MustUse(p)
+	}
+	return cfg, nil
+}
+
+// inherit 返回使用上级配置补全后的配置，c中的零值选项使用parent中的值，c.Reset中列出的选项保持零值
+func (c *Config) inherit(parent *Config) *Config {
+	if parent == nil {
+		return c
+	}
+	var merged Config
+	if c != nil {
+		merged = *c
+	}
+	reset := make(map[string]bool, len(merged.Reset))
+	for _, k := range merged.Reset {
+		reset[k] = true
+	}
+	dst := reflect.ValueOf(&merged).Elem()
+	src := reflect.ValueOf(parent).Elem()
+	for i := 0; i < dst.NumField(); i++ {
+		name := configFieldName(dst.Type().Field(i))
+		if name == "reset" || reset[name] {
+			continue
+		}
+		if f := dst.Field(i); f.IsZero() {
+			f.Set(src.Field(i))
+		}
+	}
+	return &merged
+}
+
+// configFieldName 返回配置项在配置文件中的名称，没有名称（如Sink）时使用小写的字段名
+func configFieldName(f reflect.StructField) string {
+	name := strings.Split(f.Tag.Get("json"), ",")[0]
+	if name == "" || name == "-" {
+		name = strings.ToLower(f.Name)
+	}
+	return name
+}
+
+// newOutputs 创建日志对象的输出对象，parent为最近的已注册上级日志对象，可以为nil
+// 与上级写入同一个目标的输出共享上级的输出目标，共享的日志文件的切割、清理等选项以上级为准
+func newOutputs(d *LogDefinition, parent *StdLogger) ([]*loggerOutput, error) {
+	if parent == nil {
+		return newLogOutputs(d, nil)
+	}
+	return newLogOutputs(d, parent.load().outputs)
+}
+
+// refreshChildren 上级日志对象的配置变化后，重新计算并更新全部下级日志对象的配置，返回第一个更新失败的错误
+// 按层级由上到下更新，保证共享输出目标的下级日志对象使用的是上级日志对象更新后的输出目标
+func (r *Registry) refreshChildren(name string) error {
+	prefix := name + "."
+	children := make([]string, 0)
+	r.configs.Range(func(key, value interface{}) bool {
+		if k := key.(string); strings.HasPrefix(k, prefix) {
+			children = append(children, k)
+		}
+		return true
+	})
+	sort.Slice(children, func(i, j int) bool {
+		return strings.Count(children[i], ".") < strings.Count(children[j], ".")
+	})
+	var err error
+	for _, k := range children {
+		l := r.MustUse(k)
+		value, ok := r.configs.Load(k)
+		if l == nil || !ok {
+			continue
+		}
+		cfg, parent := r.resolveConfig(k, value.(*Config))
+		e := cfg.Validate()
+		if e == nil {
+			e = l.refreshE(r.newLogDefinition(cfg), parent)
+		}
+		if e != nil && err == nil {
+			err = e
+		}
+	}
+	return err
+}
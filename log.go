@@ -3,132 +3,131 @@ package xlog
 import (
 	"encoding/json"
 	"fmt"
-	"os"
-	"runtime/debug"
 
 	"github.com/whencome/xlog/def"
-	"github.com/whencome/xlog/util"
 )
 
-func levelLog(level, data string) {
-	l := Use("default")
-	if l.def.Disabled {
-		return
-	}
-	numLevel := util.NumLogLevel(level)
-	if numLevel < l.def.Level {
-		return
-	}
-	_ = l.Output(3, level, data)
-	if l.def.LogStack && numLevel >= l.def.LogStackLevel {
-		_ = l.Output(3, level, string(debug.Stack()))
-	}
+// Enabled 判断默认日志对象在调用位置是否会记录指定等级的日志
+func Enabled(level string) bool {
+	return Use("default").enabled(2, level)
 }
 
 // Log record a specified level's log
 func Log(level string, v ...interface{}) {
-	levelLog(level, fmt.Sprint(v...))
+	Use("default").levelLog(2, level, printMode, "", v)
 }
 
 // Logf record a specified level's formatted log
 func Logf(level string, format string, v ...interface{}) {
-	levelLog(level, fmt.Sprintf(format, v...))
+	Use("default").levelLog(2, level, printfMode, format, v)
 }
 
 // Logf record a specified level's log with a new line
 func Logln(level string, v ...interface{}) {
-	levelLog(level, fmt.Sprintln(v...))
+	Use("default").levelLog(2, level, printlnMode, "", v)
+}
+
+func Trace(v ...interface{}) {
+	Use("default").levelLog(2, def.LogLevelTrace, printMode, "", v)
+}
+
+func Tracef(format string, v ...interface{}) {
+	Use("default").levelLog(2, def.LogLevelTrace, printfMode, format, v)
+}
+
+func Traceln(v ...interface{}) {
+	Use("default").levelLog(2, def.LogLevelTrace, printlnMode, "", v)
 }
 
 func Debug(v ...interface{}) {
-	Log(def.LogLevelDebug, v...)
+	Use("default").levelLog(2, def.LogLevelDebug, printMode, "", v)
 }
 
 func Debugf(format string, v ...interface{}) {
-	Logf(def.LogLevelDebug, format, v...)
+	Use("default").levelLog(2, def.LogLevelDebug, printfMode, format, v)
 }
 
 func Debugln(v ...interface{}) {
-	Logln(def.LogLevelDebug, v...)
+	Use("default").levelLog(2, def.LogLevelDebug, printlnMode, "", v)
 }
 
 func Info(v ...interface{}) {
-	Log(def.LogLevelInfo, v...)
+	Use("default").levelLog(2, def.LogLevelInfo, printMode, "", v)
 }
 
 func Infof(format string, v ...interface{}) {
-	Logf(def.LogLevelInfo, format, v...)
+	Use("default").levelLog(2, def.LogLevelInfo, printfMode, format, v)
 }
 
 func Infoln(v ...interface{}) {
-	Logln(def.LogLevelInfo, v...)
+	Use("default").levelLog(2, def.LogLevelInfo, printlnMode, "", v)
 }
 
 func Warn(v ...interface{}) {
-	Log(def.LogLevelWarn, v...)
+	Use("default").levelLog(2, def.LogLevelWarn, printMode, "", v)
 }
 
 func Warnf(format string, v ...interface{}) {
-	Logf(def.LogLevelWarn, format, v...)
+	Use("default").levelLog(2, def.LogLevelWarn, printfMode, format, v)
 }
 
 func Warnln(v ...interface{}) {
-	Logln(def.LogLevelWarn, v...)
+	Use("default").levelLog(2, def.LogLevelWarn, printlnMode, "", v)
 }
 
 func Error(v ...interface{}) {
-	Log(def.LogLevelError, v...)
+	Use("default").levelLog(2, def.LogLevelError, printMode, "", v)
 }
 
 func Errorf(format string, v ...interface{}) {
-	Logf(def.LogLevelError, format, v...)
+	Use("default").levelLog(2, def.LogLevelError, printfMode, format, v)
 }
 
 func Errorln(v ...interface{}) {
-	Logln(def.LogLevelError, v...)
+	Use("default").levelLog(2, def.LogLevelError, printlnMode, "", v)
 }
 
 func Fatal(v ...interface{}) {
-	Log(def.LogLevelFatal, v...)
-	os.Exit(1)
+	Use("default").levelLog(2, def.LogLevelFatal, printMode, "", v)
+	std.fatalExit(nil)
 }
 
 func Fatalf(format string, v ...interface{}) {
-	Logf(def.LogLevelFatal, format, v...)
-	os.Exit(1)
+	Use("default").levelLog(2, def.LogLevelFatal, printfMode, format, v)
+	std.fatalExit(nil)
 }
 
 func Fatalln(v ...interface{}) {
-	Logln(def.LogLevelFatal, v...)
-	os.Exit(1)
+	Use("default").levelLog(2, def.LogLevelFatal, printlnMode, "", v)
+	std.fatalExit(nil)
 }
 
 func Panic(v ...interface{}) {
-	Log(def.LogLevelFatal, v...)
+	Use("default").levelLog(2, def.LogLevelFatal, printMode, "", v)
 	panic(fmt.Sprint(v...))
 }
 
 func Panicf(format string, v ...interface{}) {
-	Logf(def.LogLevelFatal, format, v...)
+	Use("default").levelLog(2, def.LogLevelFatal, printfMode, format, v)
 	panic(fmt.Sprintf(format, v...))
 }
 
 func Panicln(v ...interface{}) {
-	Logln(def.LogLevelFatal, v...)
+	Use("default").levelLog(2, def.LogLevelFatal, printlnMode, "", v)
 	panic(fmt.Sprintln(v...))
 }
 
 // Raw record origin raw log
 func Raw(v ...interface{}) {
-	_ = Use("default").WriteString(fmt.Sprint(v...))
+	Use("default").Raw(v...)
 }
 
 func Rawf(format string, v ...interface{}) {
-	_ = Use("default").WriteString(fmt.Sprintf(format, v...))
+	Use("default").Rawf(format, v...)
 }
 
 func Rawln(v ...interface{}) {
-	_ = Use("default").WriteString(fmt.Sprintln(v...))
+	Use("default").Rawln(v...)
 }
 
 func Json(v interface{}) {
@@ -115,6 +115,23 @@ func (d *KVData) GetJson() string {
     return buf.String()
 }
 
+// GetLogfmt 以logfmt格式返回数据，如：query="select * from t" cost=0.91s
+func (d *KVData) GetLogfmt() string {
+    if len(d.keys) == 0 {
+        return ""
+    }
+    buf := make([]byte, 0, 64)
+    for i, k := range d.keys {
+        if i > 0 {
+            buf = append(buf, ' ')
+        }
+        util.AppendLogfmtKey(&buf, k)
+        buf = append(buf, '=')
+        util.AppendLogfmtValue(&buf, util.FormatValue(d.pairs[k]))
+    }
+    return string(buf)
+}
+
 // -------------- KVLogger ---------------
 type KVLogger struct {
     writer     io.Writer
@@ -0,0 +1,374 @@
+package xlog
+
+import (
+	"os"
+	"sort"
+	"sync"
+
+	"github.com/whencome/xlog/def"
+	"github.com/whencome/xlog/util"
+)
+
+// Registry 日志对象注册表，保存创建日志对象时使用的默认设置以及按名称注册的日志对象
+// 不同的注册表之间互不影响，如同一个程序中的不同的库、并行执行的测试可以各自使用独立的注册表
+// 包级别的函数（如Register、Use、SetLogLevel）均作用于默认注册表
+type Registry struct {
+	mu             sync.RWMutex                 // 保护默认设置、诊断回调以及退出程序的方法
+	dir            *string                      // 日志存储目录，默认注册表指向LogDir，兼容直接修改LogDir的用法
+	filePrefix     *string                      // 日志文件名前缀，默认注册表指向LogFilePrefix
+	outputType     int                          // 日志输出类型
+	output         *os.File                     // 日志输出目标
+	rotateType     int                          // 日志切割类型
+	level          int                          // 日志记录级别
+	flags          int                          // 日志格式标签
+	colorfulPrint  bool                         // 是否开启彩色打印
+	logStack       bool                         // 是否记录调用栈
+	logStackLevel  int                          // 记录调用栈的日志等级
+	defaultLogger  *StdLogger                   // 未注册default时使用的默认日志对象
+	loggers        sync.Map                     // 已注册的日志对象，名称 -> *StdLogger
+	configs        sync.Map                     // 注册时使用的原始配置，用于上级配置变化时重新计算下级的有效配置
+	strict         int32                        // 严格模式开关，1-开启
+	reported       sync.Map                     // 已经报告过的未注册的日志对象名称
+	reportedLevels sync.Map                     // 已经报告过的未注册的日志等级名称
+	diagnostic     func(err error)              // 诊断回调，为nil时输出到标准错误输出
+	exit           func(code int)               // Fatal系列方法退出程序的方法，默认为os.Exit
+	adminMu        sync.Mutex                   // 保护adminReverts
+	adminReverts   map[*loggerCore]*adminRevert // 通过AdminHandler临时修改、等待恢复的配置
+}
+
+// 默认注册表，包级别的函数均作用于该注册表
+var std = newRegistry(&LogDir, &LogFilePrefix)
+
+// NewRegistry 创建一个使用内置默认设置的注册表
+func NewRegistry() *Registry {
+	dir, prefix := "./logs", "log"
+	return newRegistry(&dir, &prefix)
+}
+
+// DefaultRegistry 返回包级别的函数使用的默认注册表
+func DefaultRegistry() *Registry {
+	return std
+}
+
+func newRegistry(dir, filePrefix *string) *Registry {
+	r := &Registry{
+		dir:           dir,
+		filePrefix:    filePrefix,
+		outputType:    def.LogToStdout,
+		output:        os.Stderr,
+		rotateType:    def.RotateByDate,
+		level:         def.LevelWarn,
+		flags:         def.LstdFlags,
+		colorfulPrint: true,
+		logStack:      true,
+		logStackLevel: def.LevelError,
+		exit:          os.Exit,
+		adminReverts:  make(map[*loggerCore]*adminRevert),
+	}
+	r.defaultLogger = createStdLogger(r.defaultLogDefinition(), nil)
+	r.defaultLogger.registry = r
+	return r
+}
+
+// defaultLogDefinition 返回使用注册表默认设置的日志定义
+func (r *Registry) defaultLogDefinition() *LogDefinition {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	d := &LogDefinition{}
+	d.Dir = *r.dir
+	d.Level = r.level
+	d.FilePrefix = *r.filePrefix
+	d.Flags = r.flags
+	d.Output = r.output
+	d.OutputType = r.outputType
+	d.RotateType = r.rotateType
+	d.LogStack = r.logStack
+	d.LogStackLevel = r.logStackLevel
+	d.ColorfulPrint = r.colorfulPrint
+	d.Disabled = false
+	return d
+}
+
+// newLogDefinition 根据配置返回日志定义，没有配置时使用注册表的默认设置
+func (r *Registry) newLogDefinition(cfg *Config) *LogDefinition {
+	if cfg == nil {
+		return r.defaultLogDefinition()
+	}
+	return newLogDefinition(cfg)
+}
+
+// SetLogFilePrefix 设置日志文件前缀
+func (r *Registry) SetLogFilePrefix(prefix string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	*r.filePrefix = prefix
+}
+
+// SetLogDir 设置日志存储目录
+func (r *Registry) SetLogDir(path string) {
+	_, _ = util.InitLogDir(path)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	*r.dir = path
+}
+
+// SetLogLevel 设置日志等级
+func (r *Registry) SetLogLevel(level string) {
+	numLevel := util.NumLogLevel(level)
+	if numLevel > def.LevelFatal {
+		numLevel = def.LevelError
+	}
+	if numLevel < def.LevelTrace {
+		numLevel = def.LevelTrace
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.level = numLevel
+}
+
+// SetLogOutputType 设置日志输出类型
+func (r *Registry) SetLogOutputType(out int) {
+	if out != def.LogToStdout && out != def.LogToStderr && out != def.LogToFile {
+		out = def.LogToStdout
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.outputType = out
+}
+
+// SetLogFlags 设置日志格式标签
+// 影响之后使用默认设置创建的日志对象，以及未注册default时使用的默认日志对象
+func (r *Registry) SetLogFlags(flag int) {
+	r.mu.Lock()
+	r.flags = flag
+	r.mu.Unlock()
+	r.defaultLogger.update(func(d *LogDefinition) {
+		d.Flags = flag
+	})
+}
+
+// SetLogRotateType 设置日志切割类型
+func (r *Registry) SetLogRotateType(t int) {
+	if t < def.RotateNone || t > def.RotateByHour {
+		t = def.RotateByDate
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.rotateType = t
+}
+
+// DisableLogStack 禁止记录调用栈信息
+func (r *Registry) DisableLogStack() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.logStack = false
+}
+
+// EnableLogStack 开启记录调用栈信息
+func (r *Registry) EnableLogStack() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.logStack = true
+}
+
+// DisableColorfulPrint 禁止彩色日志打印
+func (r *Registry) DisableColorfulPrint() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.colorfulPrint = false
+}
+
+// EnableColorfulPrint 开启彩色日志打印
+func (r *Registry) EnableColorfulPrint() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.colorfulPrint = true
+}
+
+// Init 初始化日志设置
+func (r *Registry) Init(cfg *Config) {
+	r.Register("default", cfg)
+}
+
+// InitE 初始化日志设置，配置无效或者日志文件无法打开时返回错误
+func (r *Registry) InitE(cfg *Config) error {
+	return r.RegisterE("default", cfg)
+}
+
+// InitDefault 使用默认配置初始化日志设置
+func (r *Registry) InitDefault() {
+	r.Register("default", DefaultConfig())
+}
+
+// Register 注册一个日志对象
+// 名称可以使用.分级，如order.payment，配置中未设置的选项继承最近的已注册上级日志对象的配置
+// 与上级写入同一个日志文件时共享上级的输出目标，不重复打开日志文件；需要关闭上级开启的选项时使用Config.Reset
+// 不会使用环境变量覆盖配置，需要时先调用ApplyEnv，如 cfg, err := ApplyEnv(cfg, EnvPrefix, "order")
+func (r *Registry) Register(k string, cfg *Config) {
+	r.configs.Store(k, cfg)
+	defer r.refreshChildren(k)
+	resolved, parent := r.resolveConfig(k, cfg)
+	d := r.newLogDefinition(resolved)
+	// 检查logger是否已经存在
+	if l := r.MustUse(k); l != nil {
+		l.refresh(d, parent)
+		return
+	}
+	// 创建一个新的logger
+	stdLogger := createStdLogger(d, parent)
+	stdLogger.name = k
+	stdLogger.registry = r
+	r.loggers.Store(k, stdLogger)
+}
+
+// RegisterE 注册一个日志对象，配置无效或者日志文件无法打开时返回错误
+// 日志对象已经存在时，更新失败将保持原有的配置不变
+// 注册成功后同时更新继承该配置的下级日志对象，返回第一个更新失败的错误
+func (r *Registry) RegisterE(k string, cfg *Config) error {
+	resolved, parent := r.resolveConfig(k, cfg)
+	if err := resolved.Validate(); err != nil {
+		return err
+	}
+	d := r.newLogDefinition(resolved)
+	if l := r.MustUse(k); l != nil {
+		if err := l.refreshE(d, parent); err != nil {
+			return err
+		}
+		r.configs.Store(k, cfg)
+		return r.refreshChildren(k)
+	}
+	stdLogger, err := createStdLoggerE(d, parent)
+	if err != nil {
+		return err
+	}
+	stdLogger.name = k
+	stdLogger.registry = r
+	r.configs.Store(k, cfg)
+	r.loggers.Store(k, stdLogger)
+	return r.refreshChildren(k)
+}
+
+// RegisterMany 注册多个日志对象
+func (r *Registry) RegisterMany(cfgs map[string]*Config) {
+	if cfgs == nil || len(cfgs) == 0 {
+		return
+	}
+	for k, cfg := range cfgs {
+		if cfg == nil {
+			return
+		}
+		r.Register(k, cfg)
+	}
+}
+
+// Clear 清除全部日志设置
+func (r *Registry) Clear() {
+	r.loggers.Range(func(key, value interface{}) bool {
+		if l, ok := value.(*StdLogger); ok && l != nil {
+			_ = l.Close()
+		}
+		r.loggers.Delete(key)
+		r.configs.Delete(key)
+		return true
+	})
+}
+
+// Unregister 移除指定名称的日志对象，并关闭其打开的日志文件
+// 继承该日志对象配置的下级日志对象改为继承更上一级的配置
+func (r *Registry) Unregister(k string) error {
+	l := r.MustUse(k)
+	if l == nil {
+		return nil
+	}
+	r.loggers.Delete(k)
+	r.configs.Delete(k)
+	err := l.Close()
+	if e := r.refreshChildren(k); e != nil && err == nil {
+		err = e
+	}
+	return err
+}
+
+// Names 返回全部已注册的日志对象名称，按名称排序
+func (r *Registry) Names() []string {
+	names := make([]string, 0)
+	r.loggers.Range(func(key, value interface{}) bool {
+		if k, ok := key.(string); ok {
+			names = append(names, k)
+		}
+		return true
+	})
+	sort.Strings(names)
+	return names
+}
+
+// Describe 返回Use(k)实际使用的日志对象当前生效的日志定义（已合并上级配置），返回值为副本，修改不会影响日志对象
+// k以及上级日志对象均未注册时返回默认日志对象的定义，ok为false
+func (r *Registry) Describe(k string) (d *LogDefinition, ok bool) {
+	l, ok := r.lookup(k)
+	if !ok {
+		l = r.defaultLogger
+	}
+	return l.load().def.clone(), ok
+}
+
+// ReopenAll 重新打开全部日志对象的输出目标，通常在logrotate移动日志文件后调用
+func (r *Registry) ReopenAll() error {
+	return r.each(func(l *StdLogger) error {
+		return l.Reopen()
+	})
+}
+
+// FlushAll 将全部日志对象异步队列中的日志写入输出目标，并刷新各个输出目标的缓存
+func (r *Registry) FlushAll() error {
+	return r.each(func(l *StdLogger) error {
+		return l.Flush()
+	})
+}
+
+// CloseAll 关闭全部日志对象，日志对象仍然保留在注册表中，需要移除时使用Clear
+func (r *Registry) CloseAll() error {
+	return r.each(func(l *StdLogger) error {
+		return l.Close()
+	})
+}
+
+// each 对默认日志对象以及全部已注册的日志对象执行fn，返回最后一个错误
+func (r *Registry) each(fn func(l *StdLogger) error) error {
+	err := fn(r.defaultLogger)
+	r.loggers.Range(func(key, value interface{}) bool {
+		if l, ok := value.(*StdLogger); ok && l != nil {
+			if e := fn(l); e != nil {
+				err = e
+			}
+		}
+		return true
+	})
+	return err
+}
+
+// Use 选择需要使用的日志对象
+// 日志对象未注册时使用最近的已注册上级日志对象，如order.payment.refund未注册时依次查找order.payment、order
+// 全部未注册时使用默认日志对象，开启严格模式时通过诊断回调报告，参考SetStrict
+func (r *Registry) Use(k string) *StdLogger {
+	if l, ok := r.lookup(k); ok {
+		return l
+	}
+	if k != "default" && r.strictMode() {
+		r.reportUnknown(k)
+	}
+	return r.defaultLogger
+}
+
+// MustUse 强制使用指定的日志对象，未注册时返回nil
+func (r *Registry) MustUse(k string) *StdLogger {
+	l, ok := r.loggers.Load(k)
+	if !ok {
+		return nil
+	}
+	sl, ok := l.(*StdLogger)
+	if !ok {
+		return nil
+	}
+	return sl
+}
@@ -0,0 +1,241 @@
+package xlog
+
+import (
+	"compress/gzip"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/klauspost/compress/zstd"
+
+	"github.com/whencome/xlog/def"
+)
+
+// 压缩过程中使用的临时文件后缀
+const compressTmpSuffix = ".tmp"
+
+// logFileRegexp 返回匹配当前日志定义所产生的日志文件的正则表达式
+// 文件名格式为：前缀 + 切割时间(或all) + 可选的尺寸切割序号 + .log + 可选的压缩后缀 + 可选的临时文件后缀
+func (d *LogDefinition) logFileRegexp() *regexp.Regexp {
+	mark := "all"
+	if d.RotateType != def.RotateNone {
+		mark = `\d{` + strconv.Itoa(len(d.GetLogRotateTimeFmt())) + `}`
+	}
+	return regexp.MustCompile(`^` + regexp.QuoteMeta(d.FilePrefix) + `(` + mark + `)(\.\d+)?\.log(\.gz|\.zst)?(\.tmp)?$`)
+}
+
+// matchOwnLogFile 判断文件名是否属于当前日志定义，是则返回正则匹配结果
+// 除了名称格式外，还要求时间部分是合法的时间，避免误判其他前缀相近的日志文件
+func (d *LogDefinition) matchOwnLogFile(re *regexp.Regexp, name string) []string {
+	m := re.FindStringSubmatch(name)
+	if m == nil {
+		return nil
+	}
+	if d.RotateType == def.RotateNone {
+		return m
+	}
+	if _, err := time.Parse(d.GetLogRotateTimeFmt(), m[1]); err != nil {
+		return nil
+	}
+	return m
+}
+
+// compressExt 获取压缩文件后缀
+func (d *LogDefinition) compressExt() string {
+	switch d.Compress {
+	case def.CompressGzip:
+		return ".gz"
+	case def.CompressZstd:
+		return ".zst"
+	}
+	return ""
+}
+
+// logFileExists 判断日志文件是否存在，包括已经被压缩的文件
+func logFileExists(path string) bool {
+	for _, ext := range []string{"", ".gz", ".zst"} {
+		if _, err := os.Stat(path + ext); err == nil {
+			return true
+		}
+	}
+	return false
+}
+
+// afterRotate 在日志文件切换后，异步关闭并压缩旧文件，然后清理过期的日志文件
+func (s *FileSink) afterRotate(oldFile *os.File, oldPath string) {
+	d := s.def
+	active := filepath.Base(s.path)
+	go func() {
+		_ = oldFile.Close()
+		s.janitorMu.Lock()
+		defer s.janitorMu.Unlock()
+		if d.Compress != def.CompressNone {
+			_ = compressLogFile(oldPath, d.Compress)
+		}
+		removeExpiredLogFiles(d, active)
+	}()
+}
+
+// cleanOnStart 启动（或更新配置）时处理遗留的压缩任务，并按保留策略清理一次历史日志文件
+func (s *FileSink) cleanOnStart() {
+	d := s.def
+	active := filepath.Base(s.path)
+	go func() {
+		s.janitorMu.Lock()
+		defer s.janitorMu.Unlock()
+		resumeCompress(d, active)
+		removeExpiredLogFiles(d, active)
+	}()
+}
+
+// resumeCompress 处理上次运行遗留的压缩任务
+// 删除未完成的临时压缩文件，并重新压缩尚未压缩的历史日志文件
+func resumeCompress(d *LogDefinition, active string) {
+	if d.Compress == def.CompressNone {
+		return
+	}
+	entries, err := ioutil.ReadDir(d.Dir)
+	if err != nil {
+		return
+	}
+	re := d.logFileRegexp()
+	ext := d.compressExt()
+	for _, fi := range entries {
+		m := d.matchOwnLogFile(re, fi.Name())
+		if fi.IsDir() || fi.Name() == active || m == nil {
+			continue
+		}
+		path := filepath.Join(d.Dir, fi.Name())
+		if m[4] != "" {
+			// 未完成的压缩文件
+			_ = os.Remove(path)
+			continue
+		}
+		if m[3] != "" {
+			continue
+		}
+		if _, err := os.Stat(path + ext); err == nil {
+			// 压缩文件在重命名前已完成写入和同步，只是原文件还未删除
+			_ = os.Remove(path)
+			continue
+		}
+		_ = compressLogFile(path, d.Compress)
+	}
+}
+
+// compressLogFile 压缩日志文件
+// 先写入临时文件并同步到磁盘，然后重命名为最终的压缩文件，成功后才删除原文件
+func compressLogFile(path string, method int) (err error) {
+	src, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+	fi, err := src.Stat()
+	if err != nil {
+		return err
+	}
+	var dst string
+	switch method {
+	case def.CompressGzip:
+		dst = path + ".gz"
+	case def.CompressZstd:
+		dst = path + ".zst"
+	default:
+		return nil
+	}
+	tmp := dst + compressTmpSuffix
+	f, err := os.OpenFile(tmp, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0666)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			_ = f.Close()
+			_ = os.Remove(tmp)
+		}
+	}()
+	var w io.WriteCloser
+	if method == def.CompressGzip {
+		w = gzip.NewWriter(f)
+	} else {
+		w, err = zstd.NewWriter(f)
+		if err != nil {
+			return err
+		}
+	}
+	if _, err = io.Copy(w, src); err != nil {
+		_ = w.Close()
+		return err
+	}
+	if err = w.Close(); err != nil {
+		return err
+	}
+	if err = f.Sync(); err != nil {
+		return err
+	}
+	if err = f.Close(); err != nil {
+		return err
+	}
+	// 保留原文件的修改时间，以便按时间清理
+	_ = os.Chtimes(tmp, fi.ModTime(), fi.ModTime())
+	if err = os.Rename(tmp, dst); err != nil {
+		return err
+	}
+	syncDir(filepath.Dir(dst))
+	_ = src.Close()
+	return os.Remove(path)
+}
+
+// syncDir 同步目录，保证重命名操作已经持久化，部分平台不支持，忽略错误
+func syncDir(dir string) {
+	f, err := os.Open(dir)
+	if err != nil {
+		return
+	}
+	_ = f.Sync()
+	_ = f.Close()
+}
+
+// removeExpiredLogFiles 根据MaxAge以及MaxBackups删除过期的日志文件
+// 仅处理与当前日志定义的前缀以及切割格式完全匹配的文件，正在写入的文件不会被删除
+func removeExpiredLogFiles(d *LogDefinition, active string) {
+	if d.MaxAge <= 0 && d.MaxBackups <= 0 {
+		return
+	}
+	entries, err := ioutil.ReadDir(d.Dir)
+	if err != nil {
+		return
+	}
+	re := d.logFileRegexp()
+	backups := make([]os.FileInfo, 0)
+	for _, fi := range entries {
+		if fi.IsDir() || fi.Name() == active {
+			continue
+		}
+		m := d.matchOwnLogFile(re, fi.Name())
+		if m == nil || m[4] != "" {
+			continue
+		}
+		backups = append(backups, fi)
+	}
+	// 按修改时间倒序排列，最新的文件在前
+	sort.Slice(backups, func(i, j int) bool {
+		return backups[i].ModTime().After(backups[j].ModTime())
+	})
+	now := time.Now()
+	for i, fi := range backups {
+		expired := d.MaxBackups > 0 && i >= d.MaxBackups
+		if d.MaxAge > 0 && now.Sub(fi.ModTime()) > d.MaxAge {
+			expired = true
+		}
+		if expired {
+			_ = os.Remove(filepath.Join(d.Dir, fi.Name()))
+		}
+	}
+}
@@ -0,0 +1,80 @@
+package xlog
+
+import (
+	"context"
+	"os"
+	"time"
+)
+
+// Fatal系列方法退出程序前关闭日志对象的最长等待时间
+var fatalShutdownTimeout = 5 * time.Second
+
+// SetExitFunc 设置默认注册表中的日志对象（包括通过NewStdLogger创建的日志对象）的Fatal系列方法记录日志后退出程序的方法
+// fn为nil时恢复为os.Exit，主要用于测试Fatal的调用路径，fn返回后Fatal方法也会返回
+func SetExitFunc(fn func(code int)) {
+	std.SetExitFunc(fn)
+}
+
+// SetExitFunc 设置注册表中的日志对象的Fatal系列方法记录日志后退出程序的方法，fn为nil时恢复为os.Exit
+func (r *Registry) SetExitFunc(fn func(code int)) {
+	if fn == nil {
+		fn = os.Exit
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.exit = fn
+}
+
+// Shutdown 将默认注册表中全部日志对象（包括默认日志对象）缓存以及异步队列中的日志写入输出目标，并关闭日志对象
+// ctx结束时不再等待，返回ctx.Err()，未完成的关闭操作在后台继续执行
+func Shutdown(ctx context.Context) error {
+	return std.Shutdown(ctx)
+}
+
+// Shutdown 将注册表中全部日志对象（包括默认日志对象）缓存以及异步队列中的日志写入输出目标，并关闭日志对象
+// ctx结束时不再等待，返回ctx.Err()，未完成的关闭操作在后台继续执行
+func (r *Registry) Shutdown(ctx context.Context) error {
+	return r.shutdown(ctx, nil)
+}
+
+// shutdown 关闭注册表中全部日志对象，extra为未注册的日志对象时一同关闭
+func (r *Registry) shutdown(ctx context.Context, extra *StdLogger) error {
+	done := make(chan error, 1)
+	go func() {
+		var err error
+		if extra != nil {
+			err = extra.Close()
+		}
+		if e := r.CloseAll(); e != nil {
+			err = e
+		}
+		done <- err
+	}()
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// fatalExit 关闭日志对象所属注册表中的全部日志对象后退出程序，保证Fatal日志以及缓存中的日志不会丢失
+// 未注册的日志对象关闭默认注册表中的日志对象以及自身
+func (l *StdLogger) fatalExit() {
+	r := l.registry
+	if r == nil {
+		r = std
+	}
+	r.fatalExit(l)
+}
+
+// fatalExit 关闭注册表中全部日志对象以及extra后退出程序
+func (r *Registry) fatalExit(extra *StdLogger) {
+	ctx, cancel := context.WithTimeout(context.Background(), fatalShutdownTimeout)
+	_ = r.shutdown(ctx, extra)
+	cancel()
+	r.mu.RLock()
+	fn := r.exit
+	r.mu.RUnlock()
+	fn(1)
+}
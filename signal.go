@@ -0,0 +1,40 @@
+package xlog
+
+import (
+	"os"
+	"os/signal"
+	"sync"
+)
+
+// HandleReopenSignals 监听指定的信号，收到信号后调用ReopenAll重新打开日志文件
+// 未指定信号时使用平台默认的信号（非Windows平台为SIGHUP以及SIGUSR1，Windows平台不支持）
+// 返回的函数用于停止监听，可以重复调用
+func HandleReopenSignals(sigs ...os.Signal) (stop func()) {
+	if len(sigs) == 0 {
+		sigs = reopenSignals
+	}
+	// signal.Notify未指定信号时会接收全部信号，因此没有可用的信号时直接返回
+	if len(sigs) == 0 {
+		return func() {}
+	}
+	ch := make(chan os.Signal, 1)
+	done := make(chan struct{})
+	signal.Notify(ch, sigs...)
+	go func() {
+		for {
+			select {
+			case <-ch:
+				_ = ReopenAll()
+			case <-done:
+				return
+			}
+		}
+	}()
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			signal.Stop(ch)
+			close(done)
+		})
+	}
+}
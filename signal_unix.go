@@ -0,0 +1,12 @@
+//go:build !windows
+// +build !windows
+
+package xlog
+
+import (
+	"os"
+	"syscall"
+)
+
+// 默认用于重新打开日志文件的信号
+var reopenSignals = []os.Signal{syscall.SIGHUP, syscall.SIGUSR1}
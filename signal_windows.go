@@ -0,0 +1,9 @@
+//go:build windows
+// +build windows
+
+package xlog
+
+import "os"
+
+// Windows平台不支持SIGHUP以及SIGUSR1，默认不监听任何信号
+var reopenSignals []os.Signal
@@ -0,0 +1,320 @@
+package xlog
+
+import (
+	"errors"
+	"io"
+	"os"
+	"reflect"
+	"sync"
+	"time"
+
+	"github.com/whencome/xlog/def"
+)
+
+// ErrSinkClosed 向已经关闭的输出目标写入日志时返回
+var ErrSinkClosed = errors.New("xlog: sink is closed")
+
+// Sink 日志输出目标，StdLogger将格式化后的日志写入Sink
+// 内置了标准输出、标准错误输出以及按时间/尺寸切割的文件，也可以自行实现，如发送到网络或者保存在内存中
+type Sink interface {
+	// Write 写入一条完整的日志
+	Write(p []byte) (int, error)
+	// Flush 将缓存的日志写入底层存储
+	Flush() error
+	// Close 关闭输出目标
+	Close() error
+	// Reopen 重新打开输出目标，如文件被外部程序移动后重新创建文件
+	Reopen() error
+}
+
+// ---------------- console sink ----------------
+
+// consoleSink 标准输出以及标准错误输出，不需要关闭
+type consoleSink struct {
+	f *os.File
+}
+
+var stdoutSink = &consoleSink{f: os.Stdout}
+var stderrSink = &consoleSink{f: os.Stderr}
+
+// NewStdoutSink 返回输出到标准输出的Sink
+func NewStdoutSink() Sink {
+	return stdoutSink
+}
+
+// NewStderrSink 返回输出到标准错误输出的Sink
+func NewStderrSink() Sink {
+	return stderrSink
+}
+
+func (s *consoleSink) Write(p []byte) (int, error) {
+	return s.f.Write(p)
+}
+
+func (s *consoleSink) Flush() error {
+	return nil
+}
+
+func (s *consoleSink) Close() error {
+	return nil
+}
+
+func (s *consoleSink) Reopen() error {
+	return nil
+}
+
+// ---------------- writer sink ----------------
+
+// writerSink 将任意io.Writer包装为Sink
+type writerSink struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+// NewWriterSink 将io.Writer包装为Sink
+// 如果w实现了Flush() error或者Sync() error，Flush时会调用；如果实现了io.Closer，Close时会调用
+func NewWriterSink(w io.Writer) Sink {
+	return &writerSink{w: w}
+}
+
+func (s *writerSink) Write(p []byte) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.w == nil {
+		return 0, ErrSinkClosed
+	}
+	return s.w.Write(p)
+}
+
+func (s *writerSink) Flush() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	switch x := s.w.(type) {
+	case interface{ Flush() error }:
+		return x.Flush()
+	case interface{ Sync() error }:
+		return x.Sync()
+	}
+	return nil
+}
+
+func (s *writerSink) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	x, ok := s.w.(io.Closer)
+	s.w = nil
+	if ok {
+		return x.Close()
+	}
+	return nil
+}
+
+func (s *writerSink) Reopen() error {
+	return nil
+}
+
+// ---------------- file sink ----------------
+
+// FileSink 输出到文件，支持按时间以及文件大小切割，切割后的文件可以压缩以及按保留策略清理
+type FileSink struct {
+	mu        sync.Mutex
+	janitorMu sync.Mutex     // 保证同一时间只有一个清理任务
+	def       *LogDefinition // 日志定义
+	file      *os.File       // 当前写入的文件
+	path      string         // 当前写入的文件路径
+	mark      string         // 当前时间标记，不匹配的时候就切换文件
+	index     int            // 当前日志文件按尺寸切割的序号
+	size      int64          // 当前日志文件已写入的字节数
+	info      os.FileInfo    // 当前文件的信息，用于判断文件是否被删除或者移动
+	checkedAt time.Time      // 上次检查文件是否被删除或者移动的时间
+}
+
+// 检查日志文件是否被删除或者移动的时间间隔
+const fileCheckInterval = time.Second
+
+// NewFileSink 根据配置创建一个文件输出目标，配置中仅文件相关的设置有效
+func NewFileSink(cfg *Config) (*FileSink, error) {
+	return newFileSink(newLogDefinition(cfg))
+}
+
+func newFileSink(d *LogDefinition) (*FileSink, error) {
+	s := &FileSink{def: d}
+	if err := s.open(); err != nil {
+		return nil, err
+	}
+	s.cleanOnStart()
+	return s, nil
+}
+
+// Path 返回当前写入的文件路径
+func (s *FileSink) Path() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.path
+}
+
+// calCurrentMark 计算当前时间有效标记
+func (s *FileSink) calCurrentMark() string {
+	if s.def.RotateType == def.RotateNone {
+		return ""
+	}
+	return time.Now().Format(s.def.GetLogRotateTimeFmt())
+}
+
+// open 打开当前时间段内最后一个按尺寸切割的文件
+func (s *FileSink) open() error {
+	s.mark = s.calCurrentMark()
+	s.index = s.lastFileIndex(s.mark)
+	if err := s.openFile(); err != nil {
+		return err
+	}
+	if s.def.MaxSize > 0 && s.size >= s.def.MaxSize {
+		// 最后一个文件已经写满，直接切换到下一个文件
+		_ = s.file.Close()
+		s.index++
+		return s.openFile()
+	}
+	return nil
+}
+
+// openFile 打开当前序号对应的日志文件，并记录文件已有大小
+func (s *FileSink) openFile() error {
+	path := s.def.getLogFilePath(s.mark, s.index)
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0666)
+	if err != nil {
+		return err
+	}
+	s.size = 0
+	s.info = nil
+	if fi, err := f.Stat(); err == nil {
+		s.size = fi.Size()
+		s.info = fi
+	}
+	s.file = f
+	s.path = path
+	s.checkedAt = time.Now()
+	return nil
+}
+
+// fileMoved 判断当前文件是否已经被删除或者移动（如被logrotate处理），每秒最多检查一次
+func (s *FileSink) fileMoved() bool {
+	now := time.Now()
+	if s.info == nil || now.Sub(s.checkedAt) < fileCheckInterval {
+		return false
+	}
+	s.checkedAt = now
+	fi, err := os.Stat(s.path)
+	if err != nil {
+		return os.IsNotExist(err)
+	}
+	return !os.SameFile(fi, s.info)
+}
+
+// reopen 重新打开当前的日志文件，打开成功后才关闭原来的文件
+// 无法打开时继续使用原来的文件，开启WatchFile时在下一次检查时重试
+func (s *FileSink) reopen() error {
+	oldFile, oldPath, oldMark, oldIndex, oldSize, oldInfo := s.file, s.path, s.mark, s.index, s.size, s.info
+	if err := s.open(); err != nil {
+		s.file, s.path, s.mark, s.index, s.size, s.info = oldFile, oldPath, oldMark, oldIndex, oldSize, oldInfo
+		return err
+	}
+	if oldFile != nil {
+		_ = oldFile.Close()
+	}
+	return nil
+}
+
+// lastFileIndex 获取指定时间标记下可以继续写入的最大文件序号
+func (s *FileSink) lastFileIndex(mark string) int {
+	if s.def.MaxSize <= 0 {
+		return 0
+	}
+	idx := 0
+	for logFileExists(s.def.getLogFilePath(mark, idx+1)) {
+		idx++
+	}
+	// 最后一个文件已经被压缩，则需要使用新的文件
+	if _, err := os.Stat(s.def.getLogFilePath(mark, idx)); err != nil && logFileExists(s.def.getLogFilePath(mark, idx)) {
+		idx++
+	}
+	return idx
+}
+
+// rotate 切换日志文件，新文件无法打开时继续写入原文件
+func (s *FileSink) rotate(bySize bool) {
+	oldFile, oldPath, oldMark, oldIndex, oldSize, oldInfo := s.file, s.path, s.mark, s.index, s.size, s.info
+	var err error
+	if bySize {
+		s.index++
+		err = s.openFile()
+	} else {
+		err = s.open()
+	}
+	if err != nil {
+		s.file, s.path, s.mark, s.index, s.size, s.info = oldFile, oldPath, oldMark, oldIndex, oldSize, oldInfo
+		return
+	}
+	s.afterRotate(oldFile, oldPath)
+}
+
+func (s *FileSink) Write(p []byte) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.file == nil {
+		return 0, ErrSinkClosed
+	}
+	// 文件被外部程序删除或者移动后重新打开，无法打开时继续写入原文件
+	if s.def.WatchFile && s.fileMoved() {
+		_ = s.reopen()
+	}
+	// 按时间切割
+	if s.def.RotateType != def.RotateNone && s.calCurrentMark() != s.mark {
+		s.rotate(false)
+	}
+	// 按尺寸切割，当前文件为空时不切割，避免单条日志超过限制时不断产生新文件
+	if s.def.MaxSize > 0 && s.size > 0 && s.size+int64(len(p)) > s.def.MaxSize {
+		s.rotate(true)
+	}
+	n, err := s.file.Write(p)
+	s.size += int64(n)
+	return n, err
+}
+
+func (s *FileSink) Flush() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.file == nil {
+		return nil
+	}
+	return s.file.Sync()
+}
+
+func (s *FileSink) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.file == nil {
+		return nil
+	}
+	err := s.file.Close()
+	s.file = nil
+	return err
+}
+
+// Reopen 关闭当前文件并重新打开，用于配合logrotate等外部切割工具
+func (s *FileSink) Reopen() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.reopen()
+}
+
+// sameSink 判断两个Sink是否为同一个对象
+func sameSink(a, b Sink) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	ta, tb := reflect.TypeOf(a), reflect.TypeOf(b)
+	if ta != tb || !ta.Comparable() {
+		return false
+	}
+	return a == b
+}
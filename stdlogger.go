@@ -4,10 +4,12 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
-	"os"
+	"math"
+	"path/filepath"
 	"runtime"
 	"runtime/debug"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/whencome/xlog/def"
@@ -16,178 +18,464 @@ import (
 
 // StdLogger a standard logger
 type StdLogger struct {
-	mu         sync.Mutex
+	*loggerCore         // 通过With创建的子日志对象与父日志对象共享输出、锁以及切割状态
+	fields      []field // 附加在每条日志上的字段
+}
+
+// loggerCore 日志对象的共享状态
+type loggerCore struct {
+	mu       sync.Mutex   // 保护buf以及异步队列，更新配置时也需要持有，保证同一时间只有一个更新
+	name     string       // 注册时使用的名称
+	registry *Registry    // 所属的注册表，通过NewStdLogger创建的日志对象为nil
+	state    atomic.Value // *loggerState，当前生效的配置快照
+	buf      []byte
+	async    *asyncWriter // 异步写日志对象，仅在开启异步模式时有效
+	dropped  uint64       // 已关闭的异步队列丢弃的日志数量
+
+	// 以下字段仅为兼容之前的版本保留，记录最近一次创建、更新或者关闭日志对象时第一个输出目标的信息，
+	// 不会随日志文件切割更新，修改这些字段也不会改变日志的输出目标
+
+	// Deprecated: 使用Sinks代替
 	OutputType int
-	Out        io.Writer      // 日志输出对象
-	LogFile    string         // 目标日志文件
-	ValidMark  string         // 设置有效标记，不匹配的时候就重新初始化
-	def        *LogDefinition // 日志定义
-	buf        []byte
+	// Deprecated: 使用Sinks代替
+	Out io.Writer
+	// Deprecated: 使用CurrentLogFile代替
+	LogFile string
+	// Deprecated: 仅为兼容保留
+	ValidMark string
+}
+
+// loggerState 日志对象的配置快照，创建后不再修改，更新配置时创建新的快照整体替换，因此读取时不需要加锁
+type loggerState struct {
+	def     *LogDefinition  // 日志定义
+	outputs []*loggerOutput // 日志输出对象，可以同时输出到多个目标
+	vmodule *vmodule        // 按调用位置设置的日志等级，没有配置时为nil
+}
+
+// field 附加在日志上的字段
+type field struct {
+	key   string
+	value interface{}
+}
+
+// loggerOutput 日志输出对象
+type loggerOutput struct {
+	def        *LogDefinition // 输出定义，包括日志等级、彩色打印等设置
+	outputType int            // 实际的输出类型，文件无法写入时会变为标准输出
+	sink       Sink
+	shared     bool // 是否共享上级日志对象的输出目标，共享的输出目标由上级日志对象负责重新打开以及关闭
+}
+
+// colorful 是否彩色打印，仅适用于标准输出以及标准错误输出
+func (o *loggerOutput) colorful() bool {
+	return o.def.ColorfulPrint && (o.def.OutputType == def.LogToStdout || o.def.OutputType == def.LogToStderr)
+}
+
+// accepts 判断输出目标是否输出指定等级的日志，gate为日志对象的日志等级，调用位置匹配VModule时为VModule设置的等级
+// 输出目标有自身的定义（即有多个输出目标）时，日志等级还需要不低于输出目标自身的日志等级
+func (o *loggerOutput) accepts(d *LogDefinition, gate, level int) bool {
+	return level >= gate && (o.def == d || level >= o.def.Level)
+}
+
+// isConsole 是否为标准输出或者标准错误输出
+func (o *loggerOutput) isConsole() bool {
+	return o.outputType == def.LogToStdout || o.outputType == def.LogToStderr
 }
 
 // NewStdLogger create a new StdLogger, and return its address
+// c为nil时使用默认注册表的默认设置
 func NewStdLogger(c *Config) *StdLogger {
-	def := newLogDefinition(c)
-	stdLogger := &StdLogger{
-		def: def,
-		mu:  sync.Mutex{},
-		buf: make([]byte, 1024),
+	return createStdLogger(std.newLogDefinition(c), nil)
+}
+
+// NewStdLoggerE 创建日志对象，配置无效或者日志文件无法打开时返回错误，而不是使用默认值或者输出到标准输出
+func NewStdLoggerE(c *Config) (*StdLogger, error) {
+	if err := c.Validate(); err != nil {
+		return nil, err
 	}
-	stdLogger.initOut()
+	return createStdLoggerE(std.newLogDefinition(c), nil)
+}
+
+// createStdLogger 根据日志定义创建日志对象，日志文件无法打开时输出到标准输出
+// parent不为nil时共享其输出目标，参考newOutputs
+func createStdLogger(d *LogDefinition, parent *StdLogger) *StdLogger {
+	outputs, _ := newOutputs(d, parent)
+	stdLogger := newStdLogger()
+	stdLogger.initOut(d, outputs)
 	return stdLogger
 }
 
-// 更新配置
-func (l *StdLogger) refresh(c *Config) {
-	l.def = newLogDefinition(c)
-	l.initOut()
+// createStdLoggerE 根据日志定义创建日志对象，日志文件无法打开时返回错误
+func createStdLoggerE(d *LogDefinition, parent *StdLogger) (*StdLogger, error) {
+	outputs, err := newOutputs(d, parent)
+	if err != nil {
+		closeOutputs(outputs)
+		return nil, err
+	}
+	stdLogger := newStdLogger()
+	stdLogger.initOut(d, outputs)
+	return stdLogger, nil
+}
+
+// newStdLogger 创建尚未初始化输出对象的日志对象
+func newStdLogger() *StdLogger {
+	return &StdLogger{
+		loggerCore: &loggerCore{
+			mu:  sync.Mutex{},
+			buf: make([]byte, 1024),
+		},
+	}
+}
+
+// load 返回当前生效的配置快照
+func (l *StdLogger) load() *loggerState {
+	return l.state.Load().(*loggerState)
 }
 
-// initOut 初始化输出对象
-func (l *StdLogger) initOut() {
+// With 返回一个附加了指定字段的子日志对象，kv为键值对，如 With("request_id", id, "user", uid)
+// 子日志对象与父日志对象共享输出以及配置，关闭子日志对象即关闭父日志对象
+func (l *StdLogger) With(kv ...interface{}) *StdLogger {
+	fields := make([]field, 0, len(l.fields)+(len(kv)+1)/2)
+	fields = append(fields, l.fields...)
+	fields = appendFields(fields, kv...)
+	return &StdLogger{
+		loggerCore: l.loggerCore,
+		fields:     fields,
+	}
+}
+
+// appendFields 将键值对转换为字段，键不是字符串时转换为字符串，缺少值时值为(MISSING)
+func appendFields(fields []field, kv ...interface{}) []field {
+	for i := 0; i < len(kv); i += 2 {
+		k, ok := kv[i].(string)
+		if !ok {
+			k = fmt.Sprint(kv[i])
+		}
+		var v interface{} = "(MISSING)"
+		if i+1 < len(kv) {
+			v = kv[i+1]
+		}
+		fields = append(fields, field{key: k, value: v})
+	}
+	return fields
+}
+
+// 更新配置，更新过程中持有锁，保证日志不会写入到新旧配置混合的输出目标
+func (l *StdLogger) refresh(d *LogDefinition, parent *StdLogger) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	outputs, _ := newOutputs(d, parent)
+	l.initOut(d, outputs)
+}
+
+// refreshE 更新配置，日志文件无法打开时返回错误，并保持当前的配置不变，配置需要由调用者校验
+func (l *StdLogger) refreshE(d *LogDefinition, parent *StdLogger) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	outputs, err := newOutputs(d, parent)
+	if err != nil {
+		closeOutputs(outputs)
+		return err
+	}
+	l.initOut(d, outputs)
+	return nil
+}
+
+// initOut 使用新的日志定义以及输出对象替换当前的配置快照，并关闭不再使用的输出对象
+func (l *StdLogger) initOut(d *LogDefinition, outputs []*loggerOutput) {
+	var oldOutputs []*loggerOutput
+	if old, ok := l.state.Load().(*loggerState); ok {
+		oldOutputs = old.outputs
+	}
+	l.state.Store(&loggerState{def: d, outputs: outputs, vmodule: newVModule(d.VModule)})
+	l.setLegacyFields(outputs)
+	// 异步模式，旧的队列中的日志全部写完后才能关闭之前的输出对象
+	oldAsync := l.async
+	l.async = nil
+	if d.Async {
+		l.async = newAsyncWriter(d)
+	}
+	if oldAsync != nil {
+		oldAsync.close()
+		l.dropped += oldAsync.droppedCount()
+	}
 	// 关闭之前的输出对象，以支持动态重置
-	if l.Out != nil {
-		if l.def.OutputType != l.OutputType && l.OutputType == def.LogToFile {
-			oldWriter := l.Out
-			// 关闭之前的文件
-			go func() {
-				x, ok := oldWriter.(io.Closer)
-				if ok {
-					x.Close()
-				}
-			}()
+	for _, o := range oldOutputs {
+		if o.isConsole() || o.shared || o.usedBy(outputs) {
+			continue
 		}
+		oldSink := o.sink
+		go func() {
+			_ = oldSink.Close()
+		}()
 	}
+}
+
+// newLogOutputs 根据日志定义创建全部输出对象，返回第一个日志文件无法打开的错误
+// 与shared中的输出对象写入同一个目标的输出共享该输出目标，不重复打开，也不负责重新打开以及关闭
+func newLogOutputs(d *LogDefinition, shared []*loggerOutput) ([]*loggerOutput, error) {
+	defs := d.Outputs
+	if len(defs) == 0 {
+		defs = []*LogDefinition{d}
+	}
+	var err error
+	outputs := make([]*loggerOutput, 0, len(defs))
+	for _, od := range defs {
+		if so := findOutput(shared, od); so != nil {
+			outputs = append(outputs, &loggerOutput{def: od, outputType: so.outputType, sink: so.sink, shared: true})
+			continue
+		}
+		o, e := newLogOutput(od)
+		if e != nil && err == nil {
+			err = e
+		}
+		outputs = append(outputs, o)
+	}
+	return outputs, err
+}
+
+// newLogOutput 根据输出定义创建输出对象，文件无法打开时输出到标准输出，并返回错误
+func newLogOutput(d *LogDefinition) (*loggerOutput, error) {
+	o := &loggerOutput{def: d}
+	var err error
 	// 执行初始化
-	switch l.def.OutputType {
-	case def.LogToStdout:
-		l.OutputType = def.LogToStdout
-		l.Out = os.Stdout
+	switch d.OutputType {
 	case def.LogToStderr:
-		l.OutputType = def.LogToStderr
-		l.Out = os.Stderr
+		o.outputType = def.LogToStderr
+		o.sink = stderrSink
 	case def.LogToFile:
-		// 设置日志文件
-		l.LogFile = l.def.GetLogFilePath()
-		l.ValidMark = l.calCurrentMark()
-		writer, err := os.OpenFile(l.LogFile, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0666)
+		var fileSink *FileSink
+		fileSink, err = newFileSink(d)
 		if err != nil {
 			// 如果文件无法写入，则将日志输出到标准输出
-			l.OutputType = def.LogToStdout
-			l.Out = os.Stdout
+			o.outputType = def.LogToStdout
+			o.sink = stdoutSink
+			err = fmt.Errorf("xlog: open log file failed: %v", err)
 		} else {
-			l.OutputType = def.LogToFile
-			l.Out = writer
+			o.outputType = def.LogToFile
+			o.sink = fileSink
+		}
+	case def.LogToSink:
+		o.outputType = def.LogToSink
+		o.sink = d.Sink
+	default:
+		o.outputType = def.LogToStdout
+		o.sink = stdoutSink
+	}
+	return o, err
+}
+
+// findOutput 查找与输出定义写入同一个目标（同一个日志文件或者同一个自定义输出目标）的输出对象，不存在时返回nil
+func findOutput(outputs []*loggerOutput, d *LogDefinition) *loggerOutput {
+	for _, o := range outputs {
+		switch {
+		case o.outputType == def.LogToFile && d.OutputType == def.LogToFile:
+			if filepath.Clean(o.def.Dir) == filepath.Clean(d.Dir) && o.def.FilePrefix == d.FilePrefix && o.def.RotateType == d.RotateType {
+				return o
+			}
+		case o.outputType == def.LogToSink && d.OutputType == def.LogToSink:
+			if sameSink(o.sink, d.Sink) {
+				return o
+			}
 		}
 	}
+	return nil
 }
 
-// calCurrentMark 计算当前时间有效标记
-func (l *StdLogger) calCurrentMark() string {
-	if l.def.RotateType == def.RotateNone {
-		return ""
+// closeOutputs 关闭创建失败的日志对象中已经打开的日志文件，自定义的输出目标由使用者负责关闭
+func closeOutputs(outputs []*loggerOutput) {
+	for _, o := range outputs {
+		if o.outputType == def.LogToFile && !o.shared {
+			_ = o.sink.Close()
+		}
 	}
-	return time.Now().Format(l.def.GetLogRotateTimeFmt())
 }
 
+// usedBy 判断输出目标是否仍被新的输出对象使用
+func (o *loggerOutput) usedBy(outputs []*loggerOutput) bool {
+	for _, x := range outputs {
+		if sameSink(o.sink, x.sink) {
+			return true
+		}
+	}
+	return false
+}
+
+// Sinks 返回日志对象的全部输出目标
+func (l *StdLogger) Sinks() []Sink {
+	outputs := l.load().outputs
+	sinks := make([]Sink, 0, len(outputs))
+	for _, o := range outputs {
+		sinks = append(sinks, o.sink)
+	}
+	return sinks
+}
+
+// CurrentLogFile 返回当前写入的日志文件，有多个文件时返回第一个，非文件输出时返回空字符串
+func (l *StdLogger) CurrentLogFile() string {
+	for _, sink := range l.Sinks() {
+		if fileSink, ok := sink.(*FileSink); ok {
+			return fileSink.Path()
+		}
+	}
+	return ""
+}
+
+// ungated 作为vlevel传给output时不按日志对象的日志等级过滤
+const ungated = math.MinInt32 + 1
+
 // Output write log to stdout / file
+// 不按日志对象的日志等级过滤，有多个输出目标时仍然只输出到日志等级不高于level的输出目标
 func (l *StdLogger) Output(calldepth int, level, s string) error {
-	now := time.Now()
-	var file string
-	var line int
-	l.mu.Lock()
-	defer l.mu.Unlock()
-	// if Writer is nil, then there is no need to add logs to buffer
-	if l.Out == nil {
+	if !util.IsLogLevel(level) {
+		l.reportUnknownLevel(level)
+	}
+	return l.output(calldepth+1, level, s, "", ungated)
+}
+
+// output 将日志按照各个输出目标的格式编码后输出
+// vlevel为调用位置匹配的VModule日志等级，不为noVLevel时代替日志对象的日志等级，为ungated时不按日志对象的日志等级过滤，
+// 各个输出目标自身的日志等级仍然有效
+func (l *StdLogger) output(calldepth int, level, s, stack string, vlevel int) error {
+	e := &logEntry{
+		time:   time.Now(),
+		level:  level,
+		msg:    s,
+		stack:  stack,
+		logger: l.name,
+		fields: l.fields,
+	}
+	st := l.load()
+	// if there is no output, then there is no need to add logs to buffer
+	if len(st.outputs) == 0 {
 		return nil
 	}
 	// build log prefix
-	if l.def.Flags & (def.Lshortfile | def.Llongfile) != 0 {
-		// Release lock while getting caller info - it's expensive.
-		l.mu.Unlock()
+	if st.def.Flags & (def.Lshortfile | def.Llongfile) != 0 {
 		var ok bool
-		_, file, line, ok = runtime.Caller(calldepth)
+		_, e.file, e.line, ok = runtime.Caller(calldepth)
 		if !ok {
-			file = "???"
-			line = 0
-		}
-		l.mu.Lock()
-	}
-	l.buf = l.buf[:0]
-	// colorful print begin
-	if l.def.ColorfulPrint && l.def.OutputType != def.LogToFile {
-		switch level {
-		case def.LogLevelInfo:
-			l.buf = append(l.buf, "\x1b[34m"...)
-		case def.LogLevelWarn:
-			l.buf = append(l.buf, "\x1b[33m"...)
-		case def.LogLevelError:
-			l.buf = append(l.buf, "\x1b[31m"...)
-		case def.LogLevelFatal:
-			l.buf = append(l.buf, "\x1b[35m"...)
+			e.file = "???"
+			e.line = 0
 		}
-
 	}
-	// log prefix
-	util.FormatLogPrefix(&l.buf, logFlags, now, level, file, line)
-	// log content
-	l.buf = append(l.buf, s...)
-	if len(s) == 0 || s[len(s)-1] != '\n' {
-		l.buf = append(l.buf, '\n')
+	numLevel := util.NumLogLevel(level)
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	// 加锁期间配置可能已经更新，使用最新的输出对象
+	st = l.load()
+	gate := st.def.Level
+	if vlevel != noVLevel {
+		gate = vlevel
 	}
-	// colorful print end
-	if l.def.ColorfulPrint && l.def.OutputType != def.LogToFile {
-		l.buf = append(l.buf, "\x1b[0m"...)
+	var err error
+	for _, o := range st.outputs {
+		if !o.accepts(st.def, gate, numLevel) {
+			continue
+		}
+		l.buf = l.buf[:0]
+		encodeEntry(&l.buf, o, e)
+		// 输出到文件
+		if e := l.write(o, numLevel); e != nil {
+			err = e
+		}
 	}
-	// 输出到文件
-	return l.flush()
+	return err
 }
 
 func (l *StdLogger) WriteString(s string) error {
-	if l.def.Disabled {
+	if l.load().def.Disabled {
 		return nil
 	}
 	l.mu.Lock()
 	defer l.mu.Unlock()
-	l.buf = l.buf[:0]
-	l.buf = append(l.buf, s...)
-	return l.flush()
+	var err error
+	for _, o := range l.load().outputs {
+		l.buf = l.buf[:0]
+		l.buf = append(l.buf, s...)
+		if e := l.write(o, def.LevelFatal); e != nil {
+			err = e
+		}
+	}
+	return err
 }
 
 func (l *StdLogger) Write(b []byte) (int, error) {
-	if l.def.Disabled {
+	if l.load().def.Disabled {
 		return 0, nil
 	}
+	return len(b), l.WriteString(string(b))
+}
+
+// write 将缓存中的日志写入输出目标，异步模式下放入队列由后台协程写入
+func (l *StdLogger) write(o *loggerOutput, level int) error {
+	if l.async == nil {
+		return l.flush(o)
+	}
+	if len(l.buf) == 0 || o.sink == nil {
+		return nil
+	}
+	data := make([]byte, len(l.buf))
+	copy(data, l.buf)
+	l.async.put(level, o, data)
+	l.buf = l.buf[:0]
+	return nil
+}
+
+// Flush 将异步队列中的日志全部写入输出目标，并刷新各个输出目标的缓存
+func (l *StdLogger) Flush() error {
 	l.mu.Lock()
 	defer l.mu.Unlock()
-	n := len(b)
-	l.buf = l.buf[:0]
-	l.buf = append(l.buf, b...)
-	return n, l.flush()
+	if l.async != nil {
+		l.async.drain()
+	}
+	var err error
+	for _, o := range l.load().outputs {
+		if e := o.sink.Flush(); e != nil {
+			err = e
+		}
+	}
+	return err
 }
 
-// Flush 用于将缓存中的日志内容吸入文件或者输出到标准输出设备
-func (l *StdLogger) flush() error {
-	// 计算mark，用以确认输出文件
-	if l.def.OutputType == def.LogToFile && l.def.RotateType != def.RotateNone {
-		curMark := l.calCurrentMark()
-		if curMark != l.ValidMark {
-			oldWriter := l.Out
-			l.initOut()
-			// 关闭之前的文件
-			go func() {
-				x, ok := oldWriter.(io.Closer)
-				if ok {
-					_ = x.Close()
-				}
-			}()
+// Reopen 重新打开全部输出目标，用于日志文件被logrotate等外部工具移动之后
+// 异步模式下会先将队列中的日志写入原文件
+func (l *StdLogger) Reopen() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.async != nil {
+		l.async.drain()
+	}
+	var err error
+	for _, o := range l.load().outputs {
+		if o.shared {
+			continue
+		}
+		if e := o.sink.Reopen(); e != nil {
+			err = e
 		}
 	}
+	return err
+}
+
+// Dropped 返回异步模式下因队列已满而丢弃的日志数量
+func (l *StdLogger) Dropped() uint64 {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	n := l.dropped
+	if l.async != nil {
+		n += l.async.droppedCount()
+	}
+	return n
+}
+
+// Flush 用于将缓存中的日志内容吸入文件或者输出到标准输出设备
+func (l *StdLogger) flush(o *loggerOutput) error {
 	// 输出日志
-	if len(l.buf) == 0 || l.Out == nil {
+	if len(l.buf) == 0 || o.sink == nil {
 		return nil
 	}
-	_, err := l.Out.Write(l.buf)
+	_, err := o.sink.Write(l.buf)
 	if err == nil {
 		l.buf = l.buf[:0]
 	}
@@ -196,145 +484,260 @@ func (l *StdLogger) flush() error {
 
 // Close 关闭日志对象
 func (l *StdLogger) Close() error {
-	// flush cache logs
-	err := l.flush()
-	if err != nil {
-		return err
-	}
-	// lock
 	l.mu.Lock()
 	defer l.mu.Unlock()
-	// 标准输出不需要关闭，只需要关闭文件
-	if l.def.OutputType != def.LogToFile {
-		return nil
+	// 写入异步队列中剩余的日志并停止后台协程
+	if l.async != nil {
+		l.async.close()
+		l.dropped += l.async.droppedCount()
+		l.async = nil
 	}
-	// close logger
-	x, ok := l.Out.(io.Closer)
-	if ok {
-		err = x.Close()
-		if err != nil {
-			return err
+	var err error
+	st := l.load()
+	outputs := make([]*loggerOutput, 0)
+	for _, o := range st.outputs {
+		// flush cache logs
+		if e := o.sink.Flush(); e != nil {
+			err = e
+		}
+		// 标准输出不需要关闭，只需要关闭文件等其他输出对象
+		if o.isConsole() {
+			outputs = append(outputs, o)
+			continue
+		}
+		// 共享的输出目标由上级日志对象负责关闭
+		if o.shared {
+			continue
+		}
+		if e := o.sink.Close(); e != nil {
+			err = e
 		}
 	}
-	l.Out = nil
-	return nil
+	l.state.Store(&loggerState{def: st.def, outputs: outputs, vmodule: st.vmodule})
+	l.setLegacyFields(outputs)
+	return err
 }
 
-func (l *StdLogger) levelLog(level, data string) {
-	if l.def.Disabled {
+// setLegacyFields 使用第一个输出目标的信息更新为兼容保留的字段
+func (l *StdLogger) setLegacyFields(outputs []*loggerOutput) {
+	l.OutputType, l.Out, l.LogFile, l.ValidMark = 0, nil, "", ""
+	if len(outputs) == 0 {
 		return
 	}
+	l.OutputType, l.Out = outputs[0].outputType, outputs[0].sink
+	if fileSink, ok := outputs[0].sink.(*FileSink); ok {
+		fileSink.mu.Lock()
+		l.LogFile, l.ValidMark = fileSink.path, fileSink.mark
+		fileSink.mu.Unlock()
+	}
+}
+
+// SetLevel 设置日志对象的日志等级，可以在运行时安全调用
+// 有多个输出目标时，各个输出目标仍然只输出不低于其自身日志等级的日志
+func (l *StdLogger) SetLevel(level string) {
 	numLevel := util.NumLogLevel(level)
-	if numLevel < l.def.Level {
+	l.update(func(d *LogDefinition) {
+		d.Level = numLevel
+	})
+}
+
+// SetDisabled 设置是否禁用日志对象，可以在运行时安全调用
+func (l *StdLogger) SetDisabled(disabled bool) {
+	l.update(func(d *LogDefinition) {
+		d.Disabled = disabled
+	})
+}
+
+// update 复制当前的配置快照，修改后整体替换，fn只作用于日志对象的定义
+// 各个输出目标（如打开的文件）以及其自身的定义保持不变，只有一个输出目标时输出目标的定义即日志对象的定义
+func (l *StdLogger) update(fn func(d *LogDefinition)) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	old := l.load()
+	d := *old.def
+	fn(&d)
+	outputs := make([]*loggerOutput, 0, len(old.outputs))
+	for _, o := range old.outputs {
+		od := o.def
+		if od == old.def {
+			od = &d
+		}
+		outputs = append(outputs, &loggerOutput{def: od, outputType: o.outputType, sink: o.sink, shared: o.shared})
+	}
+	l.state.Store(&loggerState{def: &d, outputs: outputs, vmodule: old.vmodule})
+}
+
+// 日志内容的格式化方式，确认需要记录日志后才进行格式化，避免被过滤的日志产生格式化开销
+const (
+	printMode   = iota // fmt.Sprint
+	printfMode         // fmt.Sprintf
+	printlnMode        // fmt.Sprintln
+)
+
+// sprint 按照指定的方式格式化日志内容
+func sprint(mode int, format string, v []interface{}) string {
+	switch mode {
+	case printfMode:
+		return fmt.Sprintf(format, v...)
+	case printlnMode:
+		return fmt.Sprintln(v...)
+	}
+	return fmt.Sprint(v...)
+}
+
+// Enabled 判断在调用位置记录指定等级的日志是否会被记录，配置了VModule时与记录日志时一样按调用位置判断
+// 被过滤的日志不会格式化，但非常量参数在调用处转换为interface{}时仍然可能产生内存分配，
+// 热点路径中可以先调用Enabled判断，如：if l.Enabled("debug") { l.Debugf("user %s", name) }
+func (l *StdLogger) Enabled(level string) bool {
+	return l.enabled(2, level)
+}
+
+// enabled 判断指定等级的日志是否会被记录，calldepth为调用者相对于本方法的栈深度
+func (l *StdLogger) enabled(calldepth int, level string) bool {
+	_, ok := l.check(calldepth+1, level)
+	return ok
+}
+
+// levelLog 记录指定等级的日志，calldepth为调用者相对于本方法的栈深度
+// 先检查日志开关以及等级，需要记录时才格式化日志内容，参数转换为v时产生的分配无法避免，参考Enabled
+func (l *StdLogger) levelLog(calldepth int, level string, mode int, format string, v []interface{}) {
+	vlevel, ok := l.check(calldepth+1, level)
+	if !ok {
 		return
 	}
-	_ = l.Output(3, level, data)
-	if l.def.LogStack && numLevel >= l.def.LogStackLevel {
-		_ = l.Output(3, level, string(debug.Stack()))
+	l.logDepth(calldepth+1, level, vlevel, sprint(mode, format, v))
+}
+
+// logDepth 记录已经格式化的日志内容，calldepth为调用者相对于本方法的栈深度
+func (l *StdLogger) logDepth(calldepth int, level string, vlevel int, data string) {
+	numLevel := util.NumLogLevel(level)
+	d := l.load().def
+	var stack string
+	if d.LogStack && numLevel >= d.LogStackLevel {
+		stack = string(debug.Stack())
 	}
+	_ = l.output(calldepth+1, level, data, stack, vlevel)
 }
 
 func (l *StdLogger) Log(level string, v ...interface{}) {
-	l.levelLog(level, fmt.Sprint(v...))
+	l.levelLog(2, level, printMode, "", v)
 }
 
 func (l *StdLogger) Logf(level string, format string, v ...interface{}) {
-	l.levelLog(level, fmt.Sprintf(format, v...))
+	l.levelLog(2, level, printfMode, format, v)
 }
 
 func (l *StdLogger) Logln(level string, v ...interface{}) {
-	l.levelLog(level, fmt.Sprintln(v...))
+	l.levelLog(2, level, printlnMode, "", v)
+}
+
+func (l *StdLogger) Trace(v ...interface{}) {
+	l.levelLog(2, def.LogLevelTrace, printMode, "", v)
+}
+
+func (l *StdLogger) Tracef(format string, v ...interface{}) {
+	l.levelLog(2, def.LogLevelTrace, printfMode, format, v)
+}
+
+func (l *StdLogger) Traceln(v ...interface{}) {
+	l.levelLog(2, def.LogLevelTrace, printlnMode, "", v)
 }
 
 func (l *StdLogger) Debug(v ...interface{}) {
-	l.Log(def.LogLevelDebug, v...)
+	l.levelLog(2, def.LogLevelDebug, printMode, "", v)
 }
 
 func (l *StdLogger) Debugf(format string, v ...interface{}) {
-	l.Logf(def.LogLevelDebug, format, v...)
+	l.levelLog(2, def.LogLevelDebug, printfMode, format, v)
 }
 
 func (l *StdLogger) Debugln(v ...interface{}) {
-	l.Logln(def.LogLevelDebug, v...)
+	l.levelLog(2, def.LogLevelDebug, printlnMode, "", v)
 }
 
 func (l *StdLogger) Info(v ...interface{}) {
-	l.Log(def.LogLevelInfo, v...)
+	l.levelLog(2, def.LogLevelInfo, printMode, "", v)
 }
 
 func (l *StdLogger) Infof(format string, v ...interface{}) {
-	l.Logf(def.LogLevelInfo, format, v...)
+	l.levelLog(2, def.LogLevelInfo, printfMode, format, v)
 }
 
 func (l *StdLogger) Infoln(v ...interface{}) {
-	l.Logln(def.LogLevelInfo, v...)
+	l.levelLog(2, def.LogLevelInfo, printlnMode, "", v)
 }
 
 func (l *StdLogger) Warn(v ...interface{}) {
-	l.Log(def.LogLevelWarn, v...)
+	l.levelLog(2, def.LogLevelWarn, printMode, "", v)
 }
 
 func (l *StdLogger) Warnf(format string, v ...interface{}) {
-	l.Logf(def.LogLevelWarn, format, v...)
+	l.levelLog(2, def.LogLevelWarn, printfMode, format, v)
 }
 
 func (l *StdLogger) Warnln(v ...interface{}) {
-	l.Logln(def.LogLevelWarn, v...)
+	l.levelLog(2, def.LogLevelWarn, printlnMode, "", v)
 }
 
 func (l *StdLogger) Error(v ...interface{}) {
-	l.Log(def.LogLevelError, v...)
+	l.levelLog(2, def.LogLevelError, printMode, "", v)
 }
 
 func (l *StdLogger) Errorf(format string, v ...interface{}) {
-	l.Logf(def.LogLevelError, format, v...)
+	l.levelLog(2, def.LogLevelError, printfMode, format, v)
 }
 
 func (l *StdLogger) Errorln(v ...interface{}) {
-	l.Logln(def.LogLevelError, v...)
+	l.levelLog(2, def.LogLevelError, printlnMode, "", v)
 }
 
 func (l *StdLogger) Fatal(v ...interface{}) {
-	l.Log(def.LogLevelFatal, v...)
-	os.Exit(1)
+	l.levelLog(2, def.LogLevelFatal, printMode, "", v)
+	l.fatalExit()
 }
 
 func (l *StdLogger) Fatalf(format string, v ...interface{}) {
-	l.Logf(def.LogLevelFatal, format, v...)
-	os.Exit(1)
+	l.levelLog(2, def.LogLevelFatal, printfMode, format, v)
+	l.fatalExit()
 }
 
 func (l *StdLogger) Fatalln(v ...interface{}) {
-	l.Logln(def.LogLevelFatal, v...)
-	os.Exit(1)
+	l.levelLog(2, def.LogLevelFatal, printlnMode, "", v)
+	l.fatalExit()
 }
 
 func (l *StdLogger) Panic(v ...interface{}) {
-	l.Log(def.LogLevelFatal, v...)
+	l.levelLog(2, def.LogLevelFatal, printMode, "", v)
 	panic(fmt.Sprint(v...))
 }
 
 func (l *StdLogger) Panicf(format string, v ...interface{}) {
-	l.Logf(def.LogLevelFatal, format, v...)
+	l.levelLog(2, def.LogLevelFatal, printfMode, format, v)
 	panic(fmt.Sprintf(format, v...))
 }
 
 func (l *StdLogger) Panicln(v ...interface{}) {
-	l.Logln(def.LogLevelFatal, v...)
+	l.levelLog(2, def.LogLevelFatal, printlnMode, "", v)
 	panic(fmt.Sprintln(v...))
 }
 
 // Raw record origin raw log
 func (l *StdLogger) Raw(v ...interface{}) {
-	_ = l.WriteString(fmt.Sprint(v...))
+	if !l.load().def.Disabled {
+		_ = l.WriteString(fmt.Sprint(v...))
+	}
 }
 
 func (l *StdLogger) Rawf(format string, v ...interface{}) {
-	_ = l.WriteString(fmt.Sprintf(format, v...))
+	if !l.load().def.Disabled {
+		_ = l.WriteString(fmt.Sprintf(format, v...))
+	}
 }
 
 func (l *StdLogger) Rawln(v ...interface{}) {
-	_ = l.WriteString(fmt.Sprintln(v...))
+	if !l.load().def.Disabled {
+		_ = l.WriteString(fmt.Sprintln(v...))
+	}
 }
 
 func (l *StdLogger) Json(v interface{}) {
@@ -1,11 +1,15 @@
 package util
 
 import (
+	"bytes"
+	"encoding/json"
 	"fmt"
 	"os"
 	"reflect"
+	"strconv"
 	"strings"
 	"time"
+	"unicode/utf8"
 
 	"github.com/whencome/xlog/def"
 )
@@ -14,6 +18,8 @@ import (
 func NumLogLevel(l string) int {
 	num := def.LevelError
 	switch l {
+	case def.LogLevelTrace:
+		num = def.LevelTrace
 	case def.LogLevelDebug:
 		num = def.LevelDebug
 	case def.LogLevelInfo:
@@ -24,10 +30,34 @@ func NumLogLevel(l string) int {
 		num = def.LevelError
 	case def.LogLevelFatal:
 		num = def.LevelFatal
+	default:
+		// 自定义的日志等级
+		if cl := customLevel(l); cl != nil {
+			num = cl.Severity
+		}
 	}
 	return num
 }
 
+// LogLevelName 获取数字日志等级对应的名称，优先使用内置的日志等级，未知的等级返回空字符串
+func LogLevelName(level int) string {
+	switch level {
+	case def.LevelTrace:
+		return def.LogLevelTrace
+	case def.LevelDebug:
+		return def.LogLevelDebug
+	case def.LevelInfo:
+		return def.LogLevelInfo
+	case def.LevelWarn:
+		return def.LogLevelWarn
+	case def.LevelError:
+		return def.LogLevelError
+	case def.LevelFatal:
+		return def.LogLevelFatal
+	}
+	return customLevelName(level)
+}
+
 // GetLogRotateTimeFmt 获取日志文件切割时间格式
 func GetLogRotateTimeFmt(logRotateType int) string {
 	var timeFmt string
@@ -44,6 +74,114 @@ func GetLogRotateTimeFmt(logRotateType int) string {
 	return timeFmt
 }
 
+// ParseSize 解析文件尺寸，如 "100MB"、"512KB"、"1G"、"1024"，不带单位时按字节计算
+// 单位不区分大小写，按1024进制换算，空字符串返回0
+func ParseSize(s string) (int64, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return 0, nil
+	}
+	unit := int64(1)
+	num := strings.ToUpper(s)
+	num = strings.TrimSuffix(num, "B")
+	switch {
+	case strings.HasSuffix(num, "K"):
+		unit = 1 << 10
+	case strings.HasSuffix(num, "M"):
+		unit = 1 << 20
+	case strings.HasSuffix(num, "G"):
+		unit = 1 << 30
+	case strings.HasSuffix(num, "T"):
+		unit = 1 << 40
+	}
+	if unit > 1 {
+		num = num[:len(num)-1]
+	}
+	n, err := strconv.ParseInt(strings.TrimSpace(num), 10, 64)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("invalid size: %q", s)
+	}
+	return n * unit, nil
+}
+
+// ParseDuration 解析时间长度，在time.ParseDuration的基础上支持以d结尾的天数，如 "7d"
+// 空字符串返回0
+func ParseDuration(s string) (time.Duration, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return 0, nil
+	}
+	if strings.HasSuffix(s, "d") {
+		n, err := strconv.ParseFloat(s[:len(s)-1], 64)
+		if err != nil || n < 0 {
+			return 0, fmt.Errorf("invalid duration: %q", s)
+		}
+		return time.Duration(n * float64(24*time.Hour)), nil
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil || d < 0 {
+		return 0, fmt.Errorf("invalid duration: %q", s)
+	}
+	return d, nil
+}
+
+// ParseFlags 解析日志格式标签，多个标签以逗号或者竖线分隔，如 "date,time,shortfile"
+// 可用的标签：date,time,microseconds,longfile,shortfile,utc,std(即date,time)，none表示不输出任何标签
+func ParseFlags(s string) (int, error) {
+	flags := 0
+	for _, name := range strings.FieldsFunc(s, func(r rune) bool {
+		return r == ',' || r == '|' || r == ' '
+	}) {
+		switch strings.ToLower(name) {
+		case "none":
+		case "date":
+			flags |= def.Ldate
+		case "time":
+			flags |= def.Ltime
+		case "microseconds":
+			flags |= def.Lmicroseconds
+		case "longfile":
+			flags |= def.Llongfile
+		case "shortfile":
+			flags |= def.Lshortfile
+		case "utc":
+			flags |= def.LUTC
+		case "std":
+			flags |= def.LstdFlags
+		default:
+			return 0, fmt.Errorf("invalid flag: %q", name)
+		}
+	}
+	return flags, nil
+}
+
+// 时间格式的名称与time包中定义的格式的对应关系
+var timeLayouts = map[string]string{
+	"ansic":       time.ANSIC,
+	"unixdate":    time.UnixDate,
+	"rubydate":    time.RubyDate,
+	"rfc822":      time.RFC822,
+	"rfc822z":     time.RFC822Z,
+	"rfc850":      time.RFC850,
+	"rfc1123":     time.RFC1123,
+	"rfc1123z":    time.RFC1123Z,
+	"rfc3339":     time.RFC3339,
+	"rfc3339nano": time.RFC3339Nano,
+	"kitchen":     time.Kitchen,
+	"stamp":       time.Stamp,
+	"stampmilli":  time.StampMilli,
+	"stampmicro":  time.StampMicro,
+	"stampnano":   time.StampNano,
+}
+
+// TimeLayout 获取时间格式，支持time包中定义的格式名称（不区分大小写），如RFC3339、RFC3339Nano，其他值原样返回
+func TimeLayout(s string) string {
+	if layout, ok := timeLayouts[strings.ToLower(s)]; ok {
+		return layout
+	}
+	return s
+}
+
 // InitLogDir 初始化日志目录
 func InitLogDir(path string) (bool, error) {
 	_, err := os.Stat(path)
@@ -89,12 +227,24 @@ func Itoa(buf *[]byte, i int, wid int) {
 
 // FormatLogPrefix 格式化日志前缀
 func FormatLogPrefix(buf *[]byte, logFlags int, t time.Time, level string, file string, line int) {
+	FormatLogPrefixLayout(buf, logFlags, "", nil, t, level, file, line)
+}
+
+// FormatLogPrefixLayout 使用指定的时间格式以及时区格式化日志前缀
+// layout不为空时代替Ldate、Ltime以及Lmicroseconds对应的时间格式，logFlags中不包含这些标签时不输出时间
+// loc不为空时使用指定的时区，否则根据LUTC决定是否使用UTC时间
+func FormatLogPrefixLayout(buf *[]byte, logFlags int, layout string, loc *time.Location, t time.Time, level string, file string, line int) {
 	// 时间
 	if logFlags & (def.Ldate | def.Ltime | def.Lmicroseconds) != 0 {
-		if logFlags & def.LUTC != 0 {
+		if loc != nil {
+			t = t.In(loc)
+		} else if logFlags & def.LUTC != 0 {
 			t = t.UTC()
 		}
-		if logFlags & def.Ldate != 0 {
+		if layout != "" {
+			*buf = t.AppendFormat(*buf, layout)
+			*buf = append(*buf, ' ')
+		} else if logFlags & def.Ldate != 0 {
 			year, month, day := t.Date()
 			Itoa(buf, year, 4)
 			*buf = append(*buf, '/')
@@ -103,7 +253,7 @@ func FormatLogPrefix(buf *[]byte, logFlags int, t time.Time, level string, file
 			Itoa(buf, day, 2)
 			*buf = append(*buf, ' ')
 		}
-		if logFlags & (def.Ltime | def.Lmicroseconds) != 0 {
+		if layout == "" && logFlags & (def.Ltime | def.Lmicroseconds) != 0 {
 			hour, min, sec := t.Clock()
 			Itoa(buf, hour, 2)
 			*buf = append(*buf, ':')
@@ -119,19 +269,12 @@ func FormatLogPrefix(buf *[]byte, logFlags int, t time.Time, level string, file
 	}
 	// 日志等级(一定会输出)
 	*buf = append(*buf, '[')
-	*buf = append(*buf, strings.ToUpper(level)...)
+	*buf = append(*buf, LevelDisplay(level)...)
 	*buf = append(*buf, "] "...)
 	// 文件
 	if logFlags & (def.Lshortfile | def.Llongfile) != 0 {
 		if logFlags & def.Lshortfile != 0 {
-			short := file
-			for i := len(file) - 1; i > 0; i-- {
-				if file[i] == '/' {
-					short = file[i+1:]
-					break
-				}
-			}
-			file = short
+			file = ShortFile(file)
 		}
 		*buf = append(*buf, file...)
 		*buf = append(*buf, ':')
@@ -140,6 +283,169 @@ func FormatLogPrefix(buf *[]byte, logFlags int, t time.Time, level string, file
 	}
 }
 
+// ShortFile 返回文件名的最后一部分，如 /a/b/c/d.go 返回 d.go
+func ShortFile(file string) string {
+	for i := len(file) - 1; i > 0; i-- {
+		if file[i] == '/' {
+			return file[i+1:]
+		}
+	}
+	return file
+}
+
+// AppendJsonString 将字符串编码为JSON字符串（包括两端的引号）追加到buf中
+// 换行符、引号以及其他控制字符都会被转义，非法的UTF-8字符替换为\ufffd
+func AppendJsonString(buf *[]byte, s string) {
+	const hex = "0123456789abcdef"
+	*buf = append(*buf, '"')
+	start := 0
+	for i := 0; i < len(s); {
+		if b := s[i]; b < utf8.RuneSelf {
+			if b >= 0x20 && b != '"' && b != '\\' && b != 0x7f {
+				i++
+				continue
+			}
+			*buf = append(*buf, s[start:i]...)
+			switch b {
+			case '"', '\\':
+				*buf = append(*buf, '\\', b)
+			case '\n':
+				*buf = append(*buf, '\\', 'n')
+			case '\r':
+				*buf = append(*buf, '\\', 'r')
+			case '\t':
+				*buf = append(*buf, '\\', 't')
+			default:
+				*buf = append(*buf, '\\', 'u', '0', '0', hex[b>>4], hex[b&0xF])
+			}
+			i++
+			start = i
+			continue
+		}
+		r, size := utf8.DecodeRuneInString(s[i:])
+		if r == utf8.RuneError && size == 1 {
+			*buf = append(*buf, s[start:i]...)
+			*buf = append(*buf, `\ufffd`...)
+			i += size
+			start = i
+			continue
+		}
+		// U+2028以及U+2029在部分JavaScript解析器中会被当作换行
+		if r == '\u2028' || r == '\u2029' {
+			*buf = append(*buf, s[start:i]...)
+			*buf = append(*buf, '\\', 'u', '2', '0', '2', hex[r&0xF])
+			i += size
+			start = i
+			continue
+		}
+		i += size
+	}
+	*buf = append(*buf, s[start:]...)
+	*buf = append(*buf, '"')
+}
+
+// AppendLogfmtKey 将logfmt的键追加到buf中，空格、等号、引号以及控制字符会被替换为下划线
+func AppendLogfmtKey(buf *[]byte, k string) {
+	if k == "" {
+		*buf = append(*buf, '_')
+		return
+	}
+	for _, r := range k {
+		if r <= ' ' || r == '=' || r == '"' || r == utf8.RuneError || r == 0x7f {
+			r = '_'
+		}
+		*buf = append(*buf, string(r)...)
+	}
+}
+
+// AppendLogfmtValue 将logfmt的值追加到buf中
+// 值为空或者包含空格、等号、引号以及控制字符时使用双引号括起来，并对引号、反斜杠以及控制字符转义
+func AppendLogfmtValue(buf *[]byte, v string) {
+	if !needsLogfmtQuote(v) {
+		*buf = append(*buf, v...)
+		return
+	}
+	AppendJsonString(buf, v)
+}
+
+// needsLogfmtQuote 判断logfmt的值是否需要使用双引号
+func needsLogfmtQuote(v string) bool {
+	if v == "" {
+		return true
+	}
+	for _, r := range v {
+		if r <= ' ' || r == '=' || r == '"' || r == '\\' || r == utf8.RuneError || r == 0x7f {
+			return true
+		}
+	}
+	return false
+}
+
+// FormatValue 将任意值转换为字符串，复合类型转换为json格式
+func FormatValue(v interface{}) string {
+	switch x := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return x
+	case []byte:
+		return string(x)
+	case error:
+		return x.Error()
+	case fmt.Stringer:
+		return x.String()
+	case bool, int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64, float32, float64:
+		return fmt.Sprint(x)
+	}
+	b, err := marshalJson(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	return string(b)
+}
+
+// AppendJsonValue 将任意值编码为JSON追加到buf中，error以及fmt.Stringer编码为字符串
+func AppendJsonValue(buf *[]byte, v interface{}) {
+	switch x := v.(type) {
+	case nil:
+		*buf = append(*buf, "null"...)
+	case string:
+		AppendJsonString(buf, x)
+	case []byte:
+		AppendJsonString(buf, string(x))
+	case error:
+		AppendJsonString(buf, x.Error())
+	case fmt.Stringer:
+		AppendJsonString(buf, x.String())
+	case bool:
+		*buf = strconv.AppendBool(*buf, x)
+	case int:
+		*buf = strconv.AppendInt(*buf, int64(x), 10)
+	case int64:
+		*buf = strconv.AppendInt(*buf, x, 10)
+	case uint64:
+		*buf = strconv.AppendUint(*buf, x, 10)
+	default:
+		b, err := marshalJson(v)
+		if err != nil {
+			AppendJsonString(buf, fmt.Sprint(v))
+			return
+		}
+		*buf = append(*buf, b...)
+	}
+}
+
+// marshalJson 编码为JSON，不转义HTML字符
+func marshalJson(v interface{}) ([]byte, error) {
+	bf := &bytes.Buffer{}
+	enc := json.NewEncoder(bf)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(v); err != nil {
+		return nil, err
+	}
+	return bytes.TrimSuffix(bf.Bytes(), []byte{'\n'}), nil
+}
+
 // IsNil 判断给定的值是否为nil
 func IsNil(i interface{}) bool {
 	ret := i == nil
@@ -0,0 +1,135 @@
+package util
+
+import (
+	"errors"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/whencome/xlog/def"
+)
+
+// CustomLevel 自定义日志等级
+type CustomLevel struct {
+	Name     string // 日志等级名称，用于配置以及Log(level, ...)，如audit
+	Severity int    // 日志等级的数值，数值越大越重要，内置等级的数值为def.LevelTrace(-1)到def.LevelFatal(4)
+	Display  string // 输出时显示的名称，为空时使用名称的大写形式
+	Color    int    // 彩色打印时使用的ANSI颜色代码，如36为青色，0表示不使用颜色
+}
+
+// 已注册的自定义日志等级，map[string]*CustomLevel，注册时复制后整体替换，读取时不需要加锁
+var (
+	customLevelsMu sync.Mutex
+	customLevels   atomic.Value
+)
+
+// RegisterLevel 注册自定义日志等级，名称已经注册时覆盖之前的设置，不能使用内置的日志等级名称
+func RegisterLevel(cl CustomLevel) error {
+	if cl.Name == "" {
+		return errors.New("xlog: level name is required")
+	}
+	if isBuiltinLevel(cl.Name) || cl.Name == "none" {
+		return errors.New("xlog: level " + cl.Name + " is reserved")
+	}
+	if cl.Display == "" {
+		cl.Display = strings.ToUpper(cl.Name)
+	}
+	customLevelsMu.Lock()
+	defer customLevelsMu.Unlock()
+	old, _ := customLevels.Load().(map[string]*CustomLevel)
+	levels := make(map[string]*CustomLevel, len(old)+1)
+	for k, v := range old {
+		levels[k] = v
+	}
+	levels[cl.Name] = &cl
+	customLevels.Store(levels)
+	return nil
+}
+
+// customLevel 获取已注册的自定义日志等级，未注册时返回nil
+func customLevel(name string) *CustomLevel {
+	levels, _ := customLevels.Load().(map[string]*CustomLevel)
+	return levels[name]
+}
+
+// customLevelName 获取数值对应的自定义日志等级名称，有多个时返回按名称排序的第一个
+func customLevelName(severity int) string {
+	levels, _ := customLevels.Load().(map[string]*CustomLevel)
+	names := make([]string, 0)
+	for name, cl := range levels {
+		if cl.Severity == severity {
+			names = append(names, name)
+		}
+	}
+	if len(names) == 0 {
+		return ""
+	}
+	sort.Strings(names)
+	return names[0]
+}
+
+// isBuiltinLevel 是否为内置的日志等级
+func isBuiltinLevel(name string) bool {
+	switch name {
+	case def.LogLevelTrace, def.LogLevelDebug, def.LogLevelInfo, def.LogLevelWarn, def.LogLevelError, def.LogLevelFatal:
+		return true
+	}
+	return false
+}
+
+// IsLogLevel 是否为内置的或者已注册的日志等级
+func IsLogLevel(name string) bool {
+	return isBuiltinLevel(name) || customLevel(name) != nil
+}
+
+// LookupLogLevel 获取日志等级对应的数字等级，ok表示是否为内置的或者已注册的日志等级，不是时返回error等级
+func LookupLogLevel(name string) (level int, ok bool) {
+	if isBuiltinLevel(name) {
+		return NumLogLevel(name), true
+	}
+	if cl := customLevel(name); cl != nil {
+		return cl.Severity, true
+	}
+	return def.LevelError, false
+}
+
+// LevelDisplay 获取日志等级输出时显示的名称，如INFO
+func LevelDisplay(name string) string {
+	switch name {
+	case def.LogLevelTrace:
+		return "TRACE"
+	case def.LogLevelDebug:
+		return "DEBUG"
+	case def.LogLevelInfo:
+		return "INFO"
+	case def.LogLevelWarn:
+		return "WARN"
+	case def.LogLevelError:
+		return "ERROR"
+	case def.LogLevelFatal:
+		return "FATAL"
+	}
+	if cl := customLevel(name); cl != nil {
+		return cl.Display
+	}
+	return strings.ToUpper(name)
+}
+
+// LevelColor 获取日志等级彩色打印时使用的ANSI颜色代码，0表示不使用颜色
+func LevelColor(name string) int {
+	switch name {
+	case def.LogLevelInfo:
+		return 34
+	case def.LogLevelWarn:
+		return 33
+	case def.LogLevelError:
+		return 31
+	case def.LogLevelFatal:
+		return 35
+	}
+	if cl := customLevel(name); cl != nil {
+		return cl.Color
+	}
+	return 0
+}
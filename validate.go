@@ -0,0 +1,166 @@
+package xlog
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/whencome/xlog/util"
+)
+
+// 配置项允许的取值
+var (
+	outputNames   = []string{"file", "stdout", "stderr"}
+	rotateNames   = []string{"none", "year", "month", "date", "hour"}
+	switchNames   = []string{"on", "off"}
+	formatNames   = []string{"text", "json", "logfmt"}
+	compressNames = []string{"none", "gzip", "zstd"}
+	overflowNames = []string{"block", "drop_newest", "drop_oldest", "drop_below"}
+)
+
+// configValidator 校验配置，记录全部错误
+type configValidator struct {
+	errs []string
+}
+
+// level 校验日志等级，可以是内置的或者已注册的日志等级，allowNone表示是否允许none
+func (v *configValidator) level(name, value string, allowNone bool) {
+	if value == "" || util.IsLogLevel(value) || (allowNone && value == "none") {
+		return
+	}
+	v.errs = append(v.errs, fmt.Sprintf("%s %q is not a registered level", name, value))
+}
+
+// enum 校验值是否为允许的取值之一，空值表示使用默认值
+func (v *configValidator) enum(name, value string, allowed []string) {
+	if value == "" {
+		return
+	}
+	for _, a := range allowed {
+		if value == a {
+			return
+		}
+	}
+	v.errs = append(v.errs, fmt.Sprintf("%s %q must be one of %s", name, value, strings.Join(allowed, ",")))
+}
+
+// size 校验文件尺寸，如100MB
+func (v *configValidator) size(name, value string) {
+	if _, err := util.ParseSize(value); err != nil {
+		v.errs = append(v.errs, fmt.Sprintf("%s %q is not a valid size", name, value))
+	}
+}
+
+// duration 校验时间长度，如7d、72h
+func (v *configValidator) duration(name, value string) {
+	if _, err := util.ParseDuration(value); err != nil {
+		v.errs = append(v.errs, fmt.Sprintf("%s %q is not a valid duration", name, value))
+	}
+}
+
+// flags 校验日志格式标签，如date,time,shortfile
+func (v *configValidator) flags(name, value string) {
+	if _, err := util.ParseFlags(value); err != nil {
+		v.errs = append(v.errs, fmt.Sprintf("%s %q: %v", name, value, err))
+	}
+}
+
+// timeZone 校验时区，如UTC、Asia/Shanghai
+func (v *configValidator) timeZone(name, value string) {
+	if value == "" {
+		return
+	}
+	if _, err := time.LoadLocation(value); err != nil {
+		v.errs = append(v.errs, fmt.Sprintf("%s %q is not a valid time zone", name, value))
+	}
+}
+
+// nonNegative 校验数值不能为负数
+func (v *configValidator) nonNegative(name string, value int) {
+	if value < 0 {
+		v.errs = append(v.errs, fmt.Sprintf("%s %d must not be negative", name, value))
+	}
+}
+
+// configField 校验配置项名称，名称为配置文件中使用的名称，如colorful_print
+func (v *configValidator) configField(name, value string) {
+	t := reflect.TypeOf(Config{})
+	for i := 0; i < t.NumField(); i++ {
+		if k := configFieldName(t.Field(i)); k == value && k != "reset" {
+			return
+		}
+	}
+	v.errs = append(v.errs, fmt.Sprintf("%s %q is not a config field", name, value))
+}
+
+// err 返回全部校验错误
+func (v *configValidator) err() error {
+	if len(v.errs) == 0 {
+		return nil
+	}
+	return errors.New("xlog: invalid config: " + strings.Join(v.errs, "; "))
+}
+
+// Validate 校验配置，返回全部无效的配置项，如日志等级写成warning、切割方式写成daily等
+// 空值表示使用默认值，不视为错误；配置为nil时使用全局设置，同样不视为错误
+func (c *Config) Validate() error {
+	if c == nil {
+		return nil
+	}
+	v := &configValidator{}
+	if c.Sink == nil && len(c.Outputs) == 0 {
+		v.enum("output", c.Output, outputNames)
+	}
+	v.level("log_level", c.LogLevel, false)
+	if _, err := parseVModule(c.VModule); err != nil {
+		v.errs = append(v.errs, "vmodule: "+err.Error())
+	}
+	v.enum("rotate", c.Rotate, rotateNames)
+	v.level("log_stack_level", c.LogStackLevel, true)
+	v.enum("switch", c.Switch, switchNames)
+	v.enum("format", c.Format, formatNames)
+	v.flags("flags", c.Flags)
+	v.timeZone("time_zone", c.TimeZone)
+	v.size("max_size", c.MaxSize)
+	v.duration("max_age", c.MaxAge)
+	v.nonNegative("max_backups", c.MaxBackups)
+	v.enum("compress", c.Compress, compressNames)
+	v.nonNegative("queue_size", c.QueueSize)
+	v.enum("overflow", c.Overflow, overflowNames)
+	v.level("overflow_level", c.OverflowLevel, false)
+	for i, k := range c.Reset {
+		v.configField(fmt.Sprintf("reset[%d]", i), k)
+	}
+	for i, o := range c.Outputs {
+		if o == nil {
+			continue
+		}
+		p := fmt.Sprintf("outputs[%d].", i)
+		if o.Sink == nil {
+			v.enum(p+"output", o.Output, outputNames)
+		}
+		v.level(p+"log_level", o.LogLevel, false)
+		v.enum(p+"format", o.Format, formatNames)
+		v.enum(p+"rotate", o.Rotate, rotateNames)
+		v.size(p+"max_size", o.MaxSize)
+		v.duration(p+"max_age", o.MaxAge)
+		v.nonNegative(p+"max_backups", o.MaxBackups)
+		v.enum(p+"compress", o.Compress, compressNames)
+	}
+	return v.err()
+}
+
+// Validate 校验配置文件中全部日志对象的配置
+func (fc *FileConfig) Validate() error {
+	if err := fc.Default.Validate(); err != nil {
+		return fmt.Errorf("default: %v", err)
+	}
+	for k, cfg := range fc.Loggers {
+		if err := cfg.Validate(); err != nil {
+			return fmt.Errorf("%s: %v", k, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,158 @@
+package xlog
+
+import (
+	"fmt"
+	"math"
+	"path"
+	"runtime"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/whencome/xlog/util"
+)
+
+// noVLevel 调用位置没有匹配的VModule规则
+const noVLevel = math.MinInt32
+
+// vmoduleRule 一条VModule规则
+type vmoduleRule struct {
+	pattern string // 文件或者包路径的匹配模式，如 payment/*.go
+	parts   int    // 匹配模式包含的路径段数
+	level   int    // 匹配时使用的日志等级
+}
+
+// vmodule 按调用位置设置的日志等级，以及按调用位置缓存的匹配结果
+type vmodule struct {
+	rules    []vmoduleRule
+	minLevel int          // 全部规则中最低的日志等级
+	mu       sync.Mutex   // 保证同一时间只有一个协程更新缓存
+	cache    atomic.Value // map[uintptr]int，调用位置对应的日志等级，更新时复制后整体替换
+}
+
+// parseVModule 解析VModule规则，多个规则以逗号分隔，如 "payment/*=debug,order.go=trace"
+func parseVModule(s string) ([]vmoduleRule, error) {
+	rules := make([]vmoduleRule, 0)
+	for _, item := range strings.Split(s, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		i := strings.LastIndex(item, "=")
+		if i <= 0 {
+			return nil, fmt.Errorf("invalid vmodule rule %q, expect pattern=level", item)
+		}
+		pattern, level := strings.TrimSpace(item[:i]), strings.TrimSpace(item[i+1:])
+		if _, err := path.Match(pattern, ""); err != nil {
+			return nil, fmt.Errorf("invalid vmodule pattern %q", pattern)
+		}
+		if !util.IsLogLevel(level) {
+			return nil, fmt.Errorf("invalid vmodule level %q", level)
+		}
+		rules = append(rules, vmoduleRule{
+			pattern: strings.Trim(pattern, "/"),
+			parts:   strings.Count(strings.Trim(pattern, "/"), "/") + 1,
+			level:   util.NumLogLevel(level),
+		})
+	}
+	return rules, nil
+}
+
+// newVModule 根据VModule规则创建按调用位置判断日志等级的对象，没有规则或者规则无效时返回nil
+func newVModule(s string) *vmodule {
+	if s == "" {
+		return nil
+	}
+	rules, err := parseVModule(s)
+	if err != nil || len(rules) == 0 {
+		return nil
+	}
+	vm := &vmodule{rules: rules, minLevel: rules[0].level}
+	for _, r := range rules {
+		if r.level < vm.minLevel {
+			vm.minLevel = r.level
+		}
+	}
+	vm.cache.Store(make(map[uintptr]int))
+	return vm
+}
+
+// levelAt 获取调用位置对应的日志等级，没有匹配的规则时返回noVLevel
+// 每个调用位置只在第一次调用时匹配规则，之后使用缓存的结果
+func (vm *vmodule) levelAt(pc uintptr) int {
+	if level, ok := vm.cache.Load().(map[uintptr]int)[pc]; ok {
+		return level
+	}
+	frame, _ := runtime.CallersFrames([]uintptr{pc}).Next()
+	level := vm.match(frame.File)
+	vm.mu.Lock()
+	defer vm.mu.Unlock()
+	old := vm.cache.Load().(map[uintptr]int)
+	cache := make(map[uintptr]int, len(old)+1)
+	for k, v := range old {
+		cache[k] = v
+	}
+	cache[pc] = level
+	vm.cache.Store(cache)
+	return level
+}
+
+// match 按顺序匹配规则，返回第一个匹配的规则的日志等级
+// 模式与文件路径的最后几段匹配，如 payment/*.go；不含.go后缀时也可以匹配文件名（如 order）或者包路径（如 payment）
+func (vm *vmodule) match(file string) int {
+	if file == "" {
+		return noVLevel
+	}
+	dir := path.Dir(file)
+	for _, r := range vm.rules {
+		f := lastPathParts(file, r.parts)
+		if ok, _ := path.Match(r.pattern, f); ok {
+			return r.level
+		}
+		if ok, _ := path.Match(r.pattern, strings.TrimSuffix(f, ".go")); ok {
+			return r.level
+		}
+		if ok, _ := path.Match(r.pattern, lastPathParts(dir, r.parts)); ok {
+			return r.level
+		}
+	}
+	return noVLevel
+}
+
+// lastPathParts 返回路径的最后n段，如 lastPathParts("/a/b/c.go", 2) 返回 b/c.go
+func lastPathParts(p string, n int) string {
+	for i := len(p) - 1; i >= 0; i-- {
+		if p[i] == '/' {
+			n--
+			if n == 0 {
+				return p[i+1:]
+			}
+		}
+	}
+	return p
+}
+
+// check 判断指定等级的日志是否需要记录，calldepth为调用者相对于本方法的栈深度
+// 配置了VModule时根据调用位置判断，同时返回调用位置匹配的日志等级，没有匹配时为noVLevel
+func (l *StdLogger) check(calldepth int, level string) (int, bool) {
+	st := l.load()
+	if st.def.Disabled {
+		return noVLevel, false
+	}
+	numLevel, known := util.LookupLogLevel(level)
+	if !known {
+		l.reportUnknownLevel(level)
+	}
+	vm := st.vmodule
+	if vm == nil || (numLevel < vm.minLevel && numLevel < st.def.Level) {
+		return noVLevel, numLevel >= st.def.Level
+	}
+	var pcs [1]uintptr
+	if runtime.Callers(calldepth+1, pcs[:]) == 0 {
+		return noVLevel, numLevel >= st.def.Level
+	}
+	if vlevel := vm.levelAt(pcs[0]); vlevel != noVLevel {
+		return vlevel, numLevel >= vlevel
+	}
+	return noVLevel, numLevel >= st.def.Level
+}
@@ -0,0 +1,117 @@
+package xlog
+
+import (
+	"os"
+	"reflect"
+	"sync"
+	"time"
+)
+
+// 默认的配置文件检查间隔
+const defaultWatchInterval = 5 * time.Second
+
+// configWatcher 定期检查配置文件，文件变化时重新加载配置
+type configWatcher struct {
+	registry *Registry
+	path     string
+	onError  func(err error)
+	modTime  time.Time   // 上次加载时文件的修改时间
+	size     int64       // 上次加载时文件的大小
+	current  *FileConfig // 当前生效的配置
+	done     chan struct{}
+}
+
+// WatchConfigFile 加载配置文件并注册其中定义的日志对象，之后定期检查配置文件，文件变化时重新加载
+// 仅更新配置发生变化的日志对象，包括日志等级、输出目标、切割方式以及开关等，从配置文件中移除的日志对象保持不变
+// 每次加载后都使用前缀为EnvPrefix的环境变量覆盖配置文件中的配置，参考ApplyEnv
+// interval为检查间隔，不大于0时使用默认的5秒；重新加载失败时调用onError（可以为nil）并继续使用当前的配置
+// 首次加载失败时返回错误，返回的stop函数用于停止检查
+func WatchConfigFile(path string, interval time.Duration, onError func(err error)) (stop func(), err error) {
+	return std.WatchConfigFile(path, interval, onError)
+}
+
+// WatchConfigFile 加载配置文件并注册到注册表中，之后定期检查配置文件，参考包级别的WatchConfigFile
+func (r *Registry) WatchConfigFile(path string, interval time.Duration, onError func(err error)) (stop func(), err error) {
+	if interval <= 0 {
+		interval = defaultWatchInterval
+	}
+	w := &configWatcher{
+		registry: r,
+		path:     path,
+		onError:  onError,
+		current:  &FileConfig{},
+		done:     make(chan struct{}),
+	}
+	if err := w.reload(); err != nil {
+		return nil, err
+	}
+	go w.run(interval)
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			close(w.done)
+		})
+	}, nil
+}
+
+// run 定期检查配置文件是否发生变化
+func (w *configWatcher) run(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			if err := w.reload(); err != nil && w.onError != nil {
+				w.onError(err)
+			}
+		case <-w.done:
+			return
+		}
+	}
+}
+
+// reload 配置文件的修改时间或者大小发生变化时重新加载，并应用有变化的配置
+func (w *configWatcher) reload() error {
+	fi, err := os.Stat(w.path)
+	if err != nil {
+		return err
+	}
+	if fi.ModTime().Equal(w.modTime) && fi.Size() == w.size {
+		return nil
+	}
+	fc, err := loadConfigFileEnv(w.path)
+	if err != nil {
+		return err
+	}
+	w.modTime, w.size = fi.ModTime(), fi.Size()
+	return w.apply(fc)
+}
+
+// apply 应用新的配置，只重新注册配置发生变化的日志对象
+// 使用InitE以及RegisterE注册，配置无效或者日志文件无法打开的日志对象保持原有的配置不变，并返回第一个错误
+func (w *configWatcher) apply(fc *FileConfig) error {
+	var err error
+	applied := &FileConfig{Default: w.current.Default, Loggers: make(map[string]*Config)}
+	for k, cfg := range w.current.Loggers {
+		applied.Loggers[k] = cfg
+	}
+	if fc.Default != nil && !reflect.DeepEqual(applied.Default, fc.Default) {
+		if err = w.registry.InitE(fc.Default); err == nil {
+			applied.Default = fc.Default
+		}
+	}
+	for k, cfg := range fc.Loggers {
+		if cfg == nil || reflect.DeepEqual(applied.Loggers[k], cfg) {
+			continue
+		}
+		if e := w.registry.RegisterE(k, cfg); e != nil {
+			if err == nil {
+				err = e
+			}
+			continue
+		}
+		applied.Loggers[k] = cfg
+	}
+	w.current = applied
+	return err
+}
@@ -5,192 +5,168 @@ package xlog
 
 import (
 	"io"
-	"os"
-	"sync"
 
-	"github.com/whencome/xlog/def"
 	"github.com/whencome/xlog/logger"
 	"github.com/whencome/xlog/util"
 )
 
 // 定义日志对象
 
-// 定义日志存储目录，默认存储在当前目录下的logs目录
+// 定义日志存储目录，默认存储在当前目录下的logs目录，仅作用于默认注册表
 var LogDir = "./logs"
 
-// 定义日志文件名前缀
+// 定义日志文件名前缀，仅作用于默认注册表
 var LogFilePrefix = "log"
 
-// 定义日志输出类型
-var logOutputType = def.LogToStdout
-
-// 定义日志输出目标
-var logOutput = os.Stderr
-
-// 定义日志切割类型
-var logRotateType = def.RotateByDate
-
-// 设置日志记录级别
-var logLevel = def.LevelWarn
-
-// 日志格式标签
-var logFlags = def.LstdFlags
-
-// 是否开启彩色打印
-var colorfulPrint = true
-
-// 设置记录调用栈的开关
-// 默认在error以及以上的级别记录调用栈，如果需要关闭调用栈，调用DisableLogStack()方法
-var logStack = true
-var logStackLevel = def.LevelError
-
-// 默认日志对象
-var defaultLogger *StdLogger = NewStdLogger(nil)
-
-// 定义日志映射列表
-var loggerMaps sync.Map
+// 默认日志对象，即默认注册表中未注册default时使用的日志对象
+var defaultLogger *StdLogger = std.defaultLogger
 
 // SetLogFilePrefix 设置日志文件前缀
 func SetLogFilePrefix(prefix string) {
-	LogFilePrefix = prefix
+	std.SetLogFilePrefix(prefix)
 }
 
 // SetLogDir 设置日志存储目录
 func SetLogDir(path string) {
-	_, _ = util.InitLogDir(path)
-	LogDir = path
+	std.SetLogDir(path)
 }
 
 // SetLogLevel 设置日志等级
 func SetLogLevel(level string) {
-	numLevel := util.NumLogLevel(level)
-	if numLevel > def.LevelFatal {
-		numLevel = def.LevelError
-	}
-	if numLevel < def.LevelDebug {
-		numLevel = def.LevelDebug
-	}
-	logLevel = numLevel
+	std.SetLogLevel(level)
+}
+
+// CustomLevel 自定义日志等级
+type CustomLevel = util.CustomLevel
+
+// RegisterLevel 注册自定义日志等级，如：
+//
+//	xlog.RegisterLevel(xlog.CustomLevel{Name: "audit", Severity: 10, Display: "AUDIT", Color: 36})
+//
+// 注册后可以用于Config中的LogLevel、LogStackLevel，以及Log("audit", ...)等方法
+// 名称已经注册时覆盖之前的设置，不能使用内置的日志等级名称
+// 使用未注册的日志等级记录日志时按照error等级记录，并通过诊断回调报告，参考SetDiagnosticHook
+func RegisterLevel(level CustomLevel) error {
+	return util.RegisterLevel(level)
 }
 
 // SetLogOutputType 设置日志输出类型
 func SetLogOutputType(out int) {
-	if out != def.LogToStdout && out != def.LogToStderr && out != def.LogToFile {
-		logOutputType = def.LogToStdout
-	}
-	logOutputType = out
+	std.SetLogOutputType(out)
 }
 
 // SetLogFlags sets the output flags for the logger.
+// 影响之后使用全局设置创建的日志对象，以及未注册default时使用的默认日志对象
 func SetLogFlags(flag int) {
-	logFlags = flag
+	std.SetLogFlags(flag)
 }
 
 // SetLogRotateType set the way to cut log files
 func SetLogRotateType(t int) {
-	if t < def.RotateNone || t > def.RotateByHour {
-		t = def.RotateByDate
-	}
-	logRotateType = t
+	std.SetLogRotateType(t)
 }
 
 // DisableLogStack 禁止记录调用栈信息
 func DisableLogStack() {
-	logStack = false
+	std.DisableLogStack()
 }
 
 // EnableLogStack 开启记录调用栈信息
 func EnableLogStack() {
-	logStack = true
+	std.EnableLogStack()
 }
 
 // DisableColorfulPrint 禁止彩色日志打印
 func DisableColorfulPrint() {
-	colorfulPrint = false
+	std.DisableColorfulPrint()
 }
 
 // EnableColorfulPrint 开启彩色日志打印
 func EnableColorfulPrint() {
-	colorfulPrint = true
+	std.EnableColorfulPrint()
 }
 
-// Init 初始化日志设置
+// Init 初始化日志设置，不会使用环境变量覆盖配置，参考Register
 func Init(cfg *Config) {
-	Register("default", cfg)
+	std.Init(cfg)
+}
+
+// InitE 初始化日志设置，配置无效或者日志文件无法打开时返回错误
+func InitE(cfg *Config) error {
+	return std.InitE(cfg)
 }
 
 // Init 初始化日志设置
 func InitDefault() {
-	Register("default", DefaultConfig())
+	std.InitDefault()
 }
 
 // 注册一个日志对象
+// 名称可以使用.分级，如order.payment，配置中未设置的选项继承最近的已注册上级日志对象的配置
+// 不会使用环境变量覆盖配置，需要时先调用ApplyEnv，如 cfg, err := ApplyEnv(cfg, EnvPrefix, "order")
 func Register(k string, cfg *Config) {
-	var stdLogger *StdLogger
-	// 检查logger是否已经存在
-	l, ok := loggerMaps.Load(k)
-	if ok && l != nil {
-		stdLogger = l.(*StdLogger)
-		stdLogger.refresh(cfg)
-		return
-	}
-	// 创建一个新的logger
-	stdLogger = NewStdLogger(cfg)
-	loggerMaps.Store(k, stdLogger)
+	std.Register(k, cfg)
+}
+
+// RegisterE 注册一个日志对象，配置无效或者日志文件无法打开时返回错误
+// 日志对象已经存在时，更新失败将保持原有的配置不变
+// 注册成功后同时更新继承该配置的下级日志对象，返回第一个更新失败的错误
+func RegisterE(k string, cfg *Config) error {
+	return std.RegisterE(k, cfg)
 }
 
 // 注册多个个日志对象
 func RegisterMany(cfgs map[string]*Config) {
-	if cfgs == nil || len(cfgs) == 0 {
-		return
-	}
-	for k, cfg := range cfgs {
-		if cfg == nil {
-			return
-		}
-		Register(k, cfg)
-	}
+	std.RegisterMany(cfgs)
 }
 
 // 清除全部日志设置
 func Clear() {
-	loggerMaps.Range(func(key, value interface{}) bool {
-		k, ok := key.(string)
-		if ok {
-			l := MustUse(k)
-			if l != nil {
-				_ = l.Close()
-			}
-		}
-		loggerMaps.Delete(key)
-		return true
-	})
+	std.Clear()
+}
+
+// Unregister 移除指定名称的日志对象，并关闭其打开的日志文件
+// 继承该日志对象配置的下级日志对象改为继承更上一级的配置
+func Unregister(k string) error {
+	return std.Unregister(k)
+}
+
+// Names 返回全部已注册的日志对象名称，按名称排序
+func Names() []string {
+	return std.Names()
+}
+
+// Describe 返回Use(k)实际使用的日志对象当前生效的日志定义（已合并上级配置），返回值为副本，修改不会影响日志对象
+// k以及上级日志对象均未注册时返回默认日志对象的定义，ok为false
+func Describe(k string) (d *LogDefinition, ok bool) {
+	return std.Describe(k)
+}
+
+// ReopenAll 重新打开全部日志对象的输出目标，通常在logrotate移动日志文件后调用
+func ReopenAll() error {
+	return std.ReopenAll()
+}
+
+// FlushAll 将全部日志对象异步队列中的日志写入输出目标，并刷新各个输出目标的缓存
+func FlushAll() error {
+	return std.FlushAll()
+}
+
+// CloseAll 关闭全部日志对象，日志对象仍然保留在注册列表中，需要移除时使用Clear
+func CloseAll() error {
+	return std.CloseAll()
 }
 
 // 选择需要使用的日志对象
+// 日志对象未注册时使用最近的已注册上级日志对象，如order.payment.refund未注册时依次查找order.payment、order
+// 全部未注册时使用默认日志对象，开启严格模式时通过诊断回调报告，参考SetStrict
 func Use(k string) *StdLogger {
-	l, ok := loggerMaps.Load(k)
-	if !ok {
-		return defaultLogger
-	}
-	sl, ok := l.(*StdLogger)
-	if !ok {
-		return defaultLogger
-	}
-	return sl
+	return std.Use(k)
 }
 
 // 强制使用指定的日志对象
 func MustUse(k string) *StdLogger {
-	l, ok := loggerMaps.Load(k)
-	if !ok {
-		return nil
-	}
-	sl, ok := l.(*StdLogger)
-	if !ok {
-		return nil
-	}
-	return sl
+	return std.MustUse(k)
 }
 
 // NewKVLogger 创建一个普通的KVLogger
@@ -3,13 +3,26 @@ package xlog
 // go test -v xlog_test.go stdlogger.go xlog.go log.go define.go
 
 import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
 	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"strings"
 	"sync"
 	"testing"
 	"time"
 
 	"github.com/whencome/xlog/def"
+	"github.com/whencome/xlog/logger"
 )
 
 func TestLog(t *testing.T) {
@@ -292,4 +305,1909 @@ func TestSwitchCfg(t *testing.T) {
 			break
 		}
 	}
-}
\ No newline at end of file
+}
+// 测试按文件大小切割日志
+func TestSizeRotate(t *testing.T) {
+	dir, err := ioutil.TempDir("", "xlog")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	cfg := &Config{
+		LogPath:       dir,
+		LogPrefix:     "size_",
+		Output:        "file",
+		LogLevel:      "debug",
+		Rotate:        "date",
+		LogStackLevel: "none",
+		MaxSize:       "1KB",
+	}
+	l := NewStdLogger(cfg)
+	for i := 0; i < 100; i++ {
+		l.Infof("size rotate test line %d", i)
+	}
+	_ = l.Close()
+	mark := time.Now().Format("20060102")
+	for _, name := range []string{"size_" + mark + ".log", "size_" + mark + ".1.log", "size_" + mark + ".2.log"} {
+		fi, err := os.Stat(filepath.Join(dir, name))
+		if err != nil {
+			t.Fatalf("expect log file %s: %v", name, err)
+		}
+		if fi.Size() > 1024 {
+			t.Fatalf("log file %s exceeds max size: %d", name, fi.Size())
+		}
+	}
+
+	// 重新打开时应当延续最后一个文件
+	l = NewStdLogger(cfg)
+	defer l.Close()
+	if l.Sinks()[0].(*FileSink).index == 0 {
+		t.Fatalf("expect to continue with last rotated file, got index 0")
+	}
+}
+
+// 测试日志文件保留策略
+func TestRetention(t *testing.T) {
+	dir, err := ioutil.TempDir("", "xlog")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	// 准备历史日志文件，以及属于其他日志对象的文件
+	old := time.Now().Add(-time.Hour * 24 * 30)
+	files := []string{"keep_20200101.log", "keep_20200102.log", "keep_20200103.1.log", "keep_20200104.log",
+		"keep_other_20200101.log", "keeper_20200101.log", "keep_note.log"}
+	for i, name := range files {
+		path := filepath.Join(dir, name)
+		_ = ioutil.WriteFile(path, []byte("old log\n"), 0666)
+		mt := old.Add(time.Duration(i) * time.Hour)
+		_ = os.Chtimes(path, mt, mt)
+	}
+	l := NewStdLogger(&Config{
+		LogPath:       dir,
+		LogPrefix:     "keep_",
+		Output:        "file",
+		LogLevel:      "debug",
+		Rotate:        "date",
+		LogStackLevel: "none",
+		MaxBackups:    2,
+	})
+	fileSink := l.Sinks()[0].(*FileSink)
+	l.Info("retention test")
+	_ = l.Close()
+	time.Sleep(time.Millisecond * 100)
+	fileSink.janitorMu.Lock()
+	defer fileSink.janitorMu.Unlock()
+	expects := map[string]bool{
+		"keep_20200101.log":       false,
+		"keep_20200102.log":       false,
+		"keep_20200103.1.log":     true,
+		"keep_20200104.log":       true,
+		"keep_other_20200101.log": true,
+		"keeper_20200101.log":     true,
+		"keep_note.log":           true,
+	}
+	for name, exists := range expects {
+		_, err := os.Stat(filepath.Join(dir, name))
+		if (err == nil) != exists {
+			t.Errorf("file %s: expect exists=%v, got error %v", name, exists, err)
+		}
+	}
+}
+
+// 测试切割后的日志文件压缩
+func TestCompress(t *testing.T) {
+	dir, err := ioutil.TempDir("", "xlog")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	// 上次运行遗留的未压缩文件以及未完成的压缩文件
+	_ = ioutil.WriteFile(filepath.Join(dir, "gz_20200101.log"), []byte("old log\n"), 0666)
+	_ = ioutil.WriteFile(filepath.Join(dir, "gz_20200102.log.gz.tmp"), []byte("broken"), 0666)
+	l := NewStdLogger(&Config{
+		LogPath:       dir,
+		LogPrefix:     "gz_",
+		Output:        "file",
+		LogLevel:      "debug",
+		Rotate:        "date",
+		LogStackLevel: "none",
+		MaxSize:       "1KB",
+		Compress:      "gzip",
+	})
+	fileSink := l.Sinks()[0].(*FileSink)
+	for i := 0; i < 30; i++ {
+		l.Infof("compress test line %d", i)
+	}
+	_ = l.Close()
+	time.Sleep(time.Millisecond * 100)
+	fileSink.janitorMu.Lock()
+	defer fileSink.janitorMu.Unlock()
+
+	mark := time.Now().Format("20060102")
+	for _, name := range []string{"gz_20200101.log.gz", "gz_" + mark + ".log.gz"} {
+		f, err := os.Open(filepath.Join(dir, name))
+		if err != nil {
+			t.Fatalf("expect compressed file %s: %v", name, err)
+		}
+		r, err := gzip.NewReader(f)
+		if err != nil {
+			t.Fatalf("invalid gzip file %s: %v", name, err)
+		}
+		if _, err = ioutil.ReadAll(r); err != nil {
+			t.Fatalf("read gzip file %s failed: %v", name, err)
+		}
+		_ = f.Close()
+	}
+	for _, name := range []string{"gz_20200101.log", "gz_20200102.log.gz.tmp", "gz_" + mark + ".log"} {
+		if _, err := os.Stat(filepath.Join(dir, name)); err == nil {
+			t.Errorf("file %s should be removed", name)
+		}
+	}
+}
+
+// 测试自定义输出目标
+func TestSink(t *testing.T) {
+	buf := &bytes.Buffer{}
+	l := NewStdLogger(&Config{
+		LogLevel:      "info",
+		LogStackLevel: "none",
+		ColorfulPrint: true,
+		Sink:          NewWriterSink(buf),
+	})
+	l.Debug("debug log")
+	l.Info("sink log")
+	if l.load().outputs[0].outputType != def.LogToSink {
+		t.Fatalf("expect output type %d, got %d", def.LogToSink, l.load().outputs[0].outputType)
+	}
+	out := buf.String()
+	if !strings.Contains(out, "[INFO] ") || !strings.Contains(out, "sink log") || strings.Contains(out, "debug log") {
+		t.Fatalf("unexpected sink output: %q", out)
+	}
+	if strings.Contains(out, "\x1b[") {
+		t.Fatalf("custom sink should not be colorful: %q", out)
+	}
+	_ = l.Close()
+	l.Info("log after closed")
+	if buf.String() != out {
+		t.Fatalf("closed logger should not write: %q", buf.String())
+	}
+}
+
+// 测试同一个日志对象输出到多个目标
+func TestMultiOutput(t *testing.T) {
+	dir, err := ioutil.TempDir("", "xlog")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	console := &bytes.Buffer{}
+	Register("order", &Config{
+		LogPath:       dir,
+		LogPrefix:     "order_",
+		LogLevel:      "info",
+		Rotate:        "date",
+		LogStackLevel: "none",
+		Outputs: []*OutputConfig{
+			{LogLevel: "debug", ColorfulPrint: true, Sink: NewWriterSink(console)},
+			{Output: "file"},
+			{Output: "file", LogLevel: "error", LogPrefix: "error_"},
+		},
+	})
+	defer Clear()
+	l := Use("order")
+	l.Debug("debug message")
+	l.Info("info message")
+	l.Error("error message")
+
+	mark := time.Now().Format("20060102")
+	orderLog, _ := ioutil.ReadFile(filepath.Join(dir, "order_"+mark+".log"))
+	errorLog, _ := ioutil.ReadFile(filepath.Join(dir, "error_"+mark+".log"))
+	expects := []struct {
+		name     string
+		content  string
+		contains []string
+		excludes []string
+	}{
+		{"console", console.String(), []string{"debug message", "info message", "error message"}, nil},
+		{"order", string(orderLog), []string{"info message", "error message"}, []string{"debug message"}},
+		{"error", string(errorLog), []string{"error message"}, []string{"debug message", "info message"}},
+	}
+	for _, e := range expects {
+		for _, s := range e.contains {
+			if !strings.Contains(e.content, s) {
+				t.Errorf("%s output should contain %q: %q", e.name, s, e.content)
+			}
+		}
+		for _, s := range e.excludes {
+			if strings.Contains(e.content, s) {
+				t.Errorf("%s output should not contain %q: %q", e.name, s, e.content)
+			}
+		}
+	}
+}
+
+// 测试Output不按日志对象的日志等级过滤，只按各个输出目标自身的日志等级过滤
+func TestOutputLevel(t *testing.T) {
+	buf := &bytes.Buffer{}
+	l := NewStdLogger(&Config{LogLevel: "error", LogStackLevel: "none", Sink: NewWriterSink(buf), Flags: "none"})
+	if err := l.Output(1, def.LogLevelInfo, "info message"); err != nil || buf.String() != "[INFO] info message\n" {
+		t.Fatalf("unexpected log: %q, %v", buf.String(), err)
+	}
+	l.Info("filtered message")
+	if buf.String() != "[INFO] info message\n" {
+		t.Fatalf("unexpected log: %q", buf.String())
+	}
+
+	buf1, buf2 := &bytes.Buffer{}, &bytes.Buffer{}
+	l = NewStdLogger(&Config{
+		LogLevel:      "error",
+		LogStackLevel: "none",
+		Flags:         "none",
+		Outputs: []*OutputConfig{
+			{Sink: NewWriterSink(buf1), LogLevel: "info"},
+			{Sink: NewWriterSink(buf2), LogLevel: "error"},
+		},
+	})
+	_ = l.Output(1, def.LogLevelInfo, "info message")
+	if buf1.String() != "[INFO] info message\n" || buf2.Len() != 0 {
+		t.Fatalf("unexpected logs: %q, %q", buf1.String(), buf2.String())
+	}
+}
+
+// 测试为兼容保留的字段
+func TestLegacyFields(t *testing.T) {
+	dir, err := ioutil.TempDir("", "xlog")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	l := NewStdLogger(&Config{Output: "file", LogPath: dir, LogPrefix: "legacy_", Rotate: "date"})
+	mark := time.Now().Format("20060102")
+	if l.OutputType != def.LogToFile || l.Out == nil || l.LogFile != l.CurrentLogFile() || l.ValidMark != mark {
+		t.Fatalf("unexpected fields: %d %v %q %q", l.OutputType, l.Out, l.LogFile, l.ValidMark)
+	}
+	_ = l.Close()
+	if l.Out != nil || l.LogFile != "" {
+		t.Fatal("expect fields cleared after Close")
+	}
+	l = NewStdLogger(&Config{Output: "stderr"})
+	if l.OutputType != def.LogToStderr || l.Out == nil || l.LogFile != "" {
+		t.Fatalf("unexpected fields: %d %v %q", l.OutputType, l.Out, l.LogFile)
+	}
+}
+
+// 测试JSON格式输出
+func TestJsonFormat(t *testing.T) {
+	buf := &bytes.Buffer{}
+	Register("json_api", &Config{
+		LogLevel:      "debug",
+		LogStackLevel: "error",
+		Format:        "json",
+		Sink:          NewWriterSink(buf),
+	})
+	defer Clear()
+	l := Use("json_api")
+	l.Info("multi\nline \"quoted\" \x01 message")
+	l.Error("error message")
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("expect 2 lines, got %d: %q", len(lines), buf.String())
+	}
+	var entry map[string]string
+	if err := json.Unmarshal([]byte(lines[0]), &entry); err != nil {
+		t.Fatalf("invalid json line %q: %v", lines[0], err)
+	}
+	if entry["msg"] != "multi\nline \"quoted\" \x01 message" || entry["level"] != "info" || entry["logger"] != "json_api" {
+		t.Fatalf("unexpected json entry: %v", entry)
+	}
+	if !strings.HasPrefix(entry["caller"], "xlog_test.go:") {
+		t.Fatalf("unexpected caller: %s", entry["caller"])
+	}
+	if _, err := time.Parse(time.RFC3339Nano, entry["time"]); err != nil {
+		t.Fatalf("unexpected time: %s", entry["time"])
+	}
+	entry = nil
+	if err := json.Unmarshal([]byte(lines[1]), &entry); err != nil {
+		t.Fatalf("invalid json line %q: %v", lines[1], err)
+	}
+	if entry["level"] != "error" || entry["stack"] == "" {
+		t.Fatalf("expect stack in error entry: %v", entry)
+	}
+}
+
+// 测试logfmt格式输出
+func TestLogfmtFormat(t *testing.T) {
+	buf := &bytes.Buffer{}
+	l := NewStdLogger(&Config{
+		LogLevel:      "debug",
+		LogStackLevel: "none",
+		Format:        "logfmt",
+		Sink:          NewWriterSink(buf),
+	})
+	l.Info(`user a=b said "hi"`)
+	line := buf.String()
+	if !strings.HasPrefix(line, "ts=") || !strings.Contains(line, " level=info caller=xlog_test.go:") ||
+		!strings.HasSuffix(line, ` msg="user a=b said \"hi\""`+"\n") {
+		t.Fatalf("unexpected logfmt line: %q", line)
+	}
+
+	data := logger.NewKVData()
+	data.Put("user", 42)
+	data.Put("query", "select * from t")
+	data.Put("empty", "")
+	data.Put("cost", "0.91s")
+	data.Put("bad key", "a=b")
+	data.Put("tags", []string{"a", "b"})
+	expect := `user=42 query="select * from t" empty="" cost=0.91s bad_key="a=b" tags="[\"a\",\"b\"]"`
+	if got := data.GetLogfmt(); got != expect {
+		t.Fatalf("unexpected logfmt data:\n%s\n%s", got, expect)
+	}
+}
+
+// 测试附加字段的子日志对象
+func TestWithFields(t *testing.T) {
+	buf := &bytes.Buffer{}
+	Register("with_api", &Config{
+		LogLevel:      "debug",
+		LogStackLevel: "none",
+		Outputs: []*OutputConfig{
+			{Format: "text", Sink: NewWriterSink(buf)},
+			{Format: "json", Sink: NewWriterSink(buf)},
+			{Format: "logfmt", Sink: NewWriterSink(buf)},
+		},
+	})
+	defer Clear()
+	parent := Use("with_api")
+	child := parent.With("request_id", "r-1", "user", 42)
+	grandchild := child.With("step", "pay now")
+	child.Infoln("child message")
+	grandchild.Info("grandchild message")
+	parent.Info("parent message")
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 9 {
+		t.Fatalf("expect 9 lines, got %d: %q", len(lines), buf.String())
+	}
+	if !strings.HasSuffix(lines[0], "child message request_id=r-1 user=42") {
+		t.Errorf("unexpected text line: %s", lines[0])
+	}
+	if !strings.HasSuffix(lines[1], `"logger":"with_api","request_id":"r-1","user":42}`) {
+		t.Errorf("unexpected json line: %s", lines[1])
+	}
+	if !strings.HasSuffix(lines[2], `msg="child message" logger=with_api request_id=r-1 user=42`) {
+		t.Errorf("unexpected logfmt line: %s", lines[2])
+	}
+	if !strings.HasSuffix(lines[3], `grandchild message request_id=r-1 user=42 step="pay now"`) {
+		t.Errorf("unexpected text line: %s", lines[3])
+	}
+	if !strings.HasSuffix(lines[6], "parent message") {
+		t.Errorf("parent should not have fields: %s", lines[6])
+	}
+}
+
+type traceKey struct{}
+
+// 测试从context中提取日志字段
+func TestContextFields(t *testing.T) {
+	RegisterContextExtractor(func(ctx context.Context) []interface{} {
+		if id, ok := ctx.Value(traceKey{}).(string); ok {
+			return []interface{}{"trace_id", id}
+		}
+		return nil
+	})
+	buf := &bytes.Buffer{}
+	l := NewStdLogger(&Config{
+		LogLevel:      "info",
+		LogStackLevel: "none",
+		Format:        "logfmt",
+		Sink:          NewWriterSink(buf),
+	})
+	ctx := NewContext(context.Background(), "request_id", "r-1")
+	ctx = context.WithValue(ctx, traceKey{}, "t-1")
+	l.InfoContext(ctx, "context message")
+	l.DebugContext(ctx, "debug message")
+	l.With("user", 42).InfofContext(NewContext(ctx, "step", 2), "context %s", "formatted")
+	l.Info("plain message")
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("expect 3 lines, got %d: %q", len(lines), buf.String())
+	}
+	if !strings.HasSuffix(lines[0], `msg="context message" request_id=r-1 trace_id=t-1`) || !strings.Contains(lines[0], "caller=xlog_test.go:") {
+		t.Errorf("unexpected line: %s", lines[0])
+	}
+	if !strings.HasSuffix(lines[1], `msg="context formatted" user=42 request_id=r-1 step=2 trace_id=t-1`) {
+		t.Errorf("unexpected line: %s", lines[1])
+	}
+	if !strings.HasSuffix(lines[2], `msg="plain message"`) {
+		t.Errorf("unexpected line: %s", lines[2])
+	}
+}
+
+// newDisabledDebugLogger 返回一个只记录error以上等级日志的日志对象
+func newDisabledDebugLogger() *StdLogger {
+	return NewStdLogger(&Config{
+		LogLevel:      "error",
+		LogStackLevel: "none",
+		Sink:          NewWriterSink(ioutil.Discard),
+	})
+}
+
+// 测试被过滤的日志不产生内存分配
+func TestFilteredLogNoAlloc(t *testing.T) {
+	l := newDisabledDebugLogger()
+	if l.Enabled(def.LogLevelDebug) || !l.Enabled(def.LogLevelError) {
+		t.Fatalf("unexpected Enabled result")
+	}
+	ctx := NewContext(context.Background(), "request_id", "r-1")
+	allocs := testing.AllocsPerRun(100, func() {
+		l.Debugf("filtered debug log: %d, %s", 42, "value")
+		l.Info("filtered info log", 42)
+		l.DebugfContext(ctx, "filtered debug log: %d", 42)
+	})
+	if allocs != 0 {
+		t.Fatalf("expect 0 allocs for filtered logs, got %v", allocs)
+	}
+
+	// 以上均为常量参数，非常量参数在调用处转换为interface{}时可能产生内存分配，与日志是否被过滤无关
+	// 热点路径中可以先使用Enabled判断，被过滤时不会产生任何分配
+	n, user := 100000+len(os.Args), strings.Repeat("u", 3)
+	allocs = testing.AllocsPerRun(100, func() {
+		if l.Enabled(def.LogLevelDebug) {
+			l.Debugf("filtered debug log: %d, %s", n, user)
+		}
+	})
+	if allocs != 0 {
+		t.Fatalf("expect 0 allocs for guarded logs, got %v", allocs)
+	}
+}
+
+func BenchmarkFilteredDebugf(b *testing.B) {
+	l := newDisabledDebugLogger()
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		l.Debugf("filtered debug log: %d, %s", 42, "value")
+	}
+}
+
+// BenchmarkFilteredDebugfArgs 使用非常量参数，参数在调用处转换为interface{}，被过滤时仍然可能产生分配
+func BenchmarkFilteredDebugfArgs(b *testing.B) {
+	l := newDisabledDebugLogger()
+	users := []string{"alice", "bob", "carol"}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		l.Debugf("filtered debug log: %d, %s", i+1000, users[i%len(users)])
+	}
+}
+
+// BenchmarkFilteredDebugfEnabled 使用Enabled判断后再记录，被过滤时不会产生分配
+func BenchmarkFilteredDebugfEnabled(b *testing.B) {
+	l := newDisabledDebugLogger()
+	users := []string{"alice", "bob", "carol"}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if l.Enabled(def.LogLevelDebug) {
+			l.Debugf("filtered debug log: %d, %s", i+1000, users[i%len(users)])
+		}
+	}
+}
+
+func BenchmarkFilteredDebugContext(b *testing.B) {
+	l := newDisabledDebugLogger()
+	ctx := NewContext(context.Background(), "request_id", "r-1")
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		l.DebugContext(ctx, "filtered debug log")
+	}
+}
+
+func BenchmarkEnabledErrorf(b *testing.B) {
+	l := newDisabledDebugLogger()
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		l.Errorf("enabled error log: %d, %s", 42, "value")
+	}
+}
+
+// gateSink 第一次写入时阻塞，直到release被关闭，用于模拟缓慢的输出目标
+type gateSink struct {
+	mu      sync.Mutex
+	lines   []string
+	started chan struct{}
+	release chan struct{}
+	once    sync.Once
+}
+
+func newGateSink() *gateSink {
+	return &gateSink{started: make(chan struct{}), release: make(chan struct{})}
+}
+
+func (s *gateSink) Write(p []byte) (int, error) {
+	s.once.Do(func() {
+		close(s.started)
+		<-s.release
+	})
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.lines = append(s.lines, strings.TrimSpace(string(p)))
+	return len(p), nil
+}
+
+func (s *gateSink) Flush() error  { return nil }
+func (s *gateSink) Close() error  { return nil }
+func (s *gateSink) Reopen() error { return nil }
+
+// 测试异步模式下队列已满时的处理方式
+func TestAsyncOverflow(t *testing.T) {
+	cases := []struct {
+		overflow string
+		expect   []string
+		dropped  uint64
+	}{
+		{"drop_newest", []string{"1", "2", "3"}, 2},
+		{"drop_oldest", []string{"1", "4", "5"}, 2},
+		{"drop_below", []string{"1", "2", "3", "5"}, 1},
+	}
+	for _, c := range cases {
+		sink := newGateSink()
+		l := NewStdLogger(&Config{
+			LogLevel:      "debug",
+			LogStackLevel: "none",
+			Format:        "logfmt",
+			Sink:          sink,
+			Async:         true,
+			QueueSize:     2,
+			Overflow:      c.overflow,
+			OverflowLevel: "error",
+		})
+		l.Info("1")
+		<-sink.started
+		l.Info("2")
+		l.Info("3")
+		l.Info("4")
+		if c.overflow == "drop_below" {
+			// 队列已满时error日志阻塞等待
+			go func() {
+				time.Sleep(time.Millisecond * 50)
+				close(sink.release)
+			}()
+			l.Error("5")
+		} else {
+			l.Info("5")
+			close(sink.release)
+		}
+		_ = l.Flush()
+		got := make([]string, 0)
+		for _, line := range sink.lines {
+			got = append(got, line[strings.LastIndex(line, "msg=")+4:])
+		}
+		if strings.Join(got, ",") != strings.Join(c.expect, ",") {
+			t.Errorf("%s: expect %v, got %v", c.overflow, c.expect, got)
+		}
+		if l.Dropped() != c.dropped {
+			t.Errorf("%s: expect %d dropped, got %d", c.overflow, c.dropped, l.Dropped())
+		}
+		_ = l.Close()
+	}
+}
+
+// 测试关闭异步日志对象时写入队列中全部日志
+func TestAsyncClose(t *testing.T) {
+	buf := &bytes.Buffer{}
+	l := NewStdLogger(&Config{
+		LogLevel:      "debug",
+		LogStackLevel: "none",
+		Sink:          NewWriterSink(buf),
+		Async:         true,
+	})
+	for i := 0; i < 1000; i++ {
+		l.Infof("async log %d", i)
+	}
+	_ = l.Close()
+	if n := strings.Count(buf.String(), "\n"); n != 1000 {
+		t.Fatalf("expect 1000 lines, got %d", n)
+	}
+}
+
+// 测试日志文件被外部程序移动后重新打开
+func TestReopen(t *testing.T) {
+	dir, err := ioutil.TempDir("", "xlog")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	l := NewStdLogger(&Config{
+		LogPath:       dir,
+		LogPrefix:     "reopen_",
+		Output:        "file",
+		LogLevel:      "debug",
+		Rotate:        "none",
+		LogStackLevel: "none",
+		WatchFile:     true,
+	})
+	defer l.Close()
+	fileSink := l.Sinks()[0].(*FileSink)
+	path := fileSink.Path()
+	l.Info("before rename")
+
+	// 显式调用Reopen
+	if err := os.Rename(path, path+".1"); err != nil {
+		t.Fatal(err)
+	}
+	if err := l.Reopen(); err != nil {
+		t.Fatal(err)
+	}
+	l.Info("after reopen")
+
+	// 自动检查文件是否被移动
+	if err := os.Rename(path, path+".2"); err != nil {
+		t.Fatal(err)
+	}
+	fileSink.mu.Lock()
+	fileSink.checkedAt = time.Time{}
+	fileSink.mu.Unlock()
+	l.Info("after watch")
+
+	for name, expect := range map[string]string{".1": "before rename", ".2": "after reopen", "": "after watch"} {
+		data, err := ioutil.ReadFile(path + name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if n := strings.Count(string(data), "\n"); n != 1 || !strings.Contains(string(data), expect) {
+			t.Fatalf("unexpected content of %s: %q", path+name, data)
+		}
+	}
+
+	// 目录被删除时无法重新打开，继续使用原文件，目录恢复后在下一次检查时重新打开
+	if err := os.RemoveAll(dir); err != nil {
+		t.Fatal(err)
+	}
+	if err := l.Reopen(); err == nil {
+		t.Fatal("expect reopen error")
+	}
+	fileSink.mu.Lock()
+	fileSink.checkedAt = time.Time{}
+	fileSink.mu.Unlock()
+	if _, err := fileSink.Write([]byte("dir removed\n")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	fileSink.mu.Lock()
+	fileSink.checkedAt = time.Time{}
+	fileSink.mu.Unlock()
+	l.Info("dir restored")
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), "dir restored") {
+		t.Fatalf("unexpected content of %s: %q", path, data)
+	}
+}
+
+// 测试从配置文件加载日志配置
+func TestLoadConfigFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "xlog")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	files := map[string]string{
+		"xlog.json": `{
+	"default": {"output": "stdout", "log_level": "info"},
+	"loggers": {
+		"order": {"log_path": "/home/logs", "log_prefix": "order_", "output": "file", "max_size": "100MB"},
+		"api": {"output": "stderr", "outputs": [{"output": "stdout", "log_level": "error"}]}
+	}
+}`,
+		"xlog.toml": `[default]
+output = "stdout"
+log_level = "info"
+
+[loggers.order]
+log_path = "/home/logs"
+log_prefix = "order_"
+output = "file"
+max_size = "100MB"
+
+[loggers.api]
+output = "stderr"
+
+[[loggers.api.outputs]]
+output = "stdout"
+log_level = "error"
+`,
+		"xlog.yml": `default:
+  output: stdout
+  log_level: info
+loggers:
+  order:
+    log_path: /home/logs
+    log_prefix: order_
+    output: file
+    max_size: 100MB
+  api:
+    output: stderr
+    outputs:
+      - output: stdout
+        log_level: error
+`,
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := ioutil.WriteFile(path, []byte(content), 0666); err != nil {
+			t.Fatal(err)
+		}
+		fc, err := LoadConfigFile(path)
+		if err != nil {
+			t.Fatalf("load %s failed: %v", name, err)
+		}
+		if fc.Default == nil || fc.Default.Output != "stdout" || fc.Default.LogLevel != "info" {
+			t.Fatalf("%s: unexpected default config: %+v", name, fc.Default)
+		}
+		order := fc.Loggers["order"]
+		if order == nil || order.LogPrefix != "order_" || order.Output != "file" || order.MaxSize != "100MB" {
+			t.Fatalf("%s: unexpected order config: %+v", name, order)
+		}
+		api := fc.Loggers["api"]
+		if api == nil || len(api.Outputs) != 1 || api.Outputs[0].LogLevel != "error" {
+			t.Fatalf("%s: unexpected api config: %+v", name, api)
+		}
+	}
+
+	// 未定义的字段以及不支持的格式
+	for name, content := range map[string]string{
+		"bad.json": `{"default": {"log_levle": "info"}}`,
+		"bad.toml": "[default]\nlog_levle = \"info\"\n",
+		"bad.yaml": "default:\n  log_levle: info\n",
+		"bad.ini":  "log_level = info\n",
+	} {
+		path := filepath.Join(dir, name)
+		_ = ioutil.WriteFile(path, []byte(content), 0666)
+		if _, err := LoadConfigFile(path); err == nil {
+			t.Errorf("%s: expect error", name)
+		}
+	}
+
+	// 注册配置文件中的日志对象
+	path := filepath.Join(dir, "init.yaml")
+	_ = ioutil.WriteFile(path, []byte("loggers:\n  file_cfg:\n    output: stdout\n    log_level: error\n"), 0666)
+	if err := InitFromFile(path); err != nil {
+		t.Fatal(err)
+	}
+	defer Clear()
+	l := MustUse("file_cfg")
+	if l == nil {
+		t.Fatal("logger file_cfg not registered")
+	}
+	if l.Enabled(def.LogLevelWarn) || !l.Enabled(def.LogLevelError) {
+		t.Fatal("unexpected log level of file_cfg")
+	}
+}
+
+// 测试配置文件变化后自动更新日志对象
+func TestWatchConfigFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "xlog")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "watch.yaml")
+	writeConfig := func(content string, mtime time.Time) {
+		if err := ioutil.WriteFile(path, []byte(content), 0666); err != nil {
+			t.Fatal(err)
+		}
+		_ = os.Chtimes(path, mtime, mtime)
+	}
+	now := time.Now()
+	writeConfig("loggers:\n  watch:\n    output: stdout\n    log_level: error\n", now.Add(-time.Hour))
+
+	errCh := make(chan error, 10)
+	stop, err := WatchConfigFile(path, 10*time.Millisecond, func(err error) {
+		errCh <- err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stop()
+	defer Clear()
+	l := MustUse("watch")
+	if l == nil || l.Enabled(def.LogLevelWarn) {
+		t.Fatal("logger watch not registered with level error")
+	}
+
+	// 修改日志等级
+	writeConfig("loggers:\n  watch:\n    output: stdout\n    log_level: debug\n", now.Add(-time.Minute))
+	deadline := time.Now().Add(2 * time.Second)
+	for !l.Enabled(def.LogLevelDebug) {
+		if time.Now().After(deadline) {
+			t.Fatal("config not reloaded")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if MustUse("watch") != l {
+		t.Fatal("logger should be refreshed in place")
+	}
+
+	// 配置文件错误时保留当前的配置
+	writeConfig("loggers:\n  watch:\n    log_level: [\n", now)
+	select {
+	case <-errCh:
+	case <-time.After(2 * time.Second):
+		t.Fatal("expect parse error")
+	}
+	if !l.Enabled(def.LogLevelDebug) {
+		t.Fatal("working config should be kept")
+	}
+
+	// 配置无效或者日志文件无法打开时保留当前的配置
+	for i, content := range []string{
+		"loggers:\n  watch:\n    output: stdout\n    log_level: verbose\n",
+		"loggers:\n  watch:\n    output: file\n    log_level: info\n    log_path: " + filepath.Join(path, "sub") + "\n",
+	} {
+		writeConfig(content, now.Add(time.Duration(i+1)*time.Second))
+		select {
+		case <-errCh:
+		case <-time.After(2 * time.Second):
+			t.Fatalf("expect error for config: %s", content)
+		}
+		if !l.Enabled(def.LogLevelDebug) || l.CurrentLogFile() != "" {
+			t.Fatalf("working config should be kept: %s", content)
+		}
+	}
+
+	if _, err := WatchConfigFile(filepath.Join(dir, "missing.yaml"), 0, nil); err == nil {
+		t.Fatal("expect error for missing config file")
+	}
+}
+
+// 测试并发记录日志的同时更新配置，需要使用 go test -race 运行
+func TestConcurrentRefresh(t *testing.T) {
+	dir, err := ioutil.TempDir("", "xlog")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	cfgs := []*Config{
+		{LogPath: dir, LogPrefix: "race_", Output: "file", LogLevel: "debug", Rotate: "none", LogStackLevel: "none"},
+		{LogPath: dir, LogPrefix: "race_", Output: "file", LogLevel: "info", Rotate: "none", LogStackLevel: "none", Async: true},
+		{LogLevel: "error", Sink: NewWriterSink(ioutil.Discard), Format: "json"},
+		{LogPath: dir, LogPrefix: "race_", LogLevel: "debug", Rotate: "none", LogStackLevel: "none", Outputs: []*OutputConfig{
+			{Output: "file", LogLevel: "debug"},
+			{Sink: NewWriterSink(ioutil.Discard), LogLevel: "warn"},
+		}},
+		{LogLevel: "debug", Switch: "off", Sink: NewWriterSink(ioutil.Discard)},
+	}
+	Register("race", cfgs[0])
+	defer Clear()
+	l := MustUse("race")
+	child := l.With("worker", 1)
+
+	var wg sync.WaitGroup
+	done := make(chan struct{})
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for {
+				select {
+				case <-done:
+					return
+				default:
+				}
+				l.Debugf("race log %d", i)
+				child.Info("race child log")
+				l.Raw("race raw log\n")
+				_ = l.Enabled(def.LogLevelWarn)
+				_ = l.CurrentLogFile()
+			}
+		}(i)
+	}
+	for i := 0; i < 50; i++ {
+		Register("race", cfgs[i%len(cfgs)])
+		l.SetLevel(def.LogLevelWarn)
+		l.SetDisabled(i%2 == 0)
+		if i%10 == 0 {
+			_ = l.Flush()
+			_ = l.Reopen()
+		}
+	}
+	close(done)
+	wg.Wait()
+
+	l.SetDisabled(false)
+	l.SetLevel(def.LogLevelError)
+	if l.Enabled(def.LogLevelWarn) || !l.Enabled(def.LogLevelError) {
+		t.Fatal("SetLevel not applied")
+	}
+	l.SetDisabled(true)
+	if l.Enabled(def.LogLevelFatal) || child.Enabled(def.LogLevelFatal) {
+		t.Fatal("SetDisabled not applied")
+	}
+}
+
+// 测试SetLevel同时作用于全部输出目标，且不会重新创建输出目标
+func TestSetLevel(t *testing.T) {
+	buf1, buf2 := &bytes.Buffer{}, &bytes.Buffer{}
+	l := NewStdLogger(&Config{
+		LogStackLevel: "none",
+		Outputs: []*OutputConfig{
+			{Sink: NewWriterSink(buf1), LogLevel: "debug"},
+			{Sink: NewWriterSink(buf2), LogLevel: "error"},
+		},
+	})
+	sinks := l.Sinks()
+	l.Info("info before")
+	l.SetLevel(def.LogLevelWarn)
+	l.Info("info after")
+	l.Warn("warn after")
+	if !strings.Contains(buf1.String(), "info before") || strings.Contains(buf1.String(), "info after") {
+		t.Fatalf("unexpected output: %q", buf1.String())
+	}
+	if !strings.Contains(buf1.String(), "warn after") || strings.Contains(buf2.String(), "warn after") {
+		t.Fatalf("output levels should be kept: %q, %q", buf1.String(), buf2.String())
+	}
+	// 降低日志等级时各个输出目标仍然使用自身的日志等级
+	l.SetLevel(def.LogLevelDebug)
+	l.Debug("debug after")
+	l.Error("error after")
+	if !strings.Contains(buf1.String(), "debug after") || strings.Contains(buf2.String(), "debug after") || !strings.Contains(buf2.String(), "error after") {
+		t.Fatalf("output levels should be kept: %q, %q", buf1.String(), buf2.String())
+	}
+	for i, s := range l.Sinks() {
+		if s != sinks[i] {
+			t.Fatal("sinks should not be recreated")
+		}
+	}
+}
+
+// 测试通过http接口查看以及修改日志对象
+func TestAdminHandler(t *testing.T) {
+	Register("admin", &Config{LogLevel: "warn", LogStackLevel: "none", Sink: NewWriterSink(ioutil.Discard)})
+	defer Clear()
+	l := MustUse("admin")
+	srv := httptest.NewServer(AdminHandler())
+	defer srv.Close()
+
+	request := func(method, query string, v interface{}) int {
+		req, _ := http.NewRequest(method, srv.URL+"?"+query, nil)
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		if v != nil {
+			if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+				t.Fatal(err)
+			}
+		}
+		return resp.StatusCode
+	}
+
+	var infos []*LoggerInfo
+	if code := request(http.MethodGet, "", &infos); code != http.StatusOK {
+		t.Fatalf("unexpected status %d", code)
+	}
+	found := false
+	for _, info := range infos {
+		if info.Name == "admin" {
+			found = true
+			if info.Level != "warn" || info.Switch != "on" || len(info.Outputs) != 1 || info.Outputs[0].Output != "sink" {
+				t.Fatalf("unexpected logger info: %+v", info)
+			}
+		}
+	}
+	if !found {
+		t.Fatal("logger admin not listed")
+	}
+
+	// 临时修改日志等级，到期后恢复
+	var info LoggerInfo
+	if code := request(http.MethodPut, "name=admin&level=debug&ttl=100ms", &info); code != http.StatusOK {
+		t.Fatalf("unexpected status %d", code)
+	}
+	if info.Level != "debug" || info.RevertAt == nil || !l.Enabled(def.LogLevelDebug) {
+		t.Fatalf("level not changed: %+v", info)
+	}
+	// 再次临时修改，恢复时仍然恢复到最初的配置
+	if code := request(http.MethodPost, "name=admin&switch=off&ttl=100ms", &info); code != http.StatusOK {
+		t.Fatalf("unexpected status %d", code)
+	}
+	if info.Switch != "off" || l.Enabled(def.LogLevelFatal) {
+		t.Fatalf("switch not changed: %+v", info)
+	}
+	deadline := time.Now().Add(2 * time.Second)
+	for l.Enabled(def.LogLevelDebug) || !l.Enabled(def.LogLevelWarn) {
+		if time.Now().After(deadline) {
+			t.Fatal("config not reverted")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	// 永久修改
+	if code := request(http.MethodPut, "name=admin&level=error", nil); code != http.StatusOK {
+		t.Fatalf("unexpected status %d", code)
+	}
+	if l.Enabled(def.LogLevelWarn) || !l.Enabled(def.LogLevelError) {
+		t.Fatal("level not changed")
+	}
+
+	// 修改日志等级不影响各个输出目标自身的日志等级
+	buf1, buf2 := &bytes.Buffer{}, &bytes.Buffer{}
+	Register("admin_multi", &Config{
+		LogStackLevel: "none",
+		Flags:         "none",
+		Outputs: []*OutputConfig{
+			{Sink: NewWriterSink(buf1), LogLevel: "debug"},
+			{Sink: NewWriterSink(buf2), LogLevel: "error"},
+		},
+	})
+	if code := request(http.MethodPut, "name=admin_multi&level=error", nil); code != http.StatusOK {
+		t.Fatalf("unexpected status %d", code)
+	}
+	if code := request(http.MethodPut, "name=admin_multi&level=debug&ttl=100ms", &info); code != http.StatusOK {
+		t.Fatalf("unexpected status %d", code)
+	}
+	if info.Level != "debug" || len(info.Outputs) != 2 || info.Outputs[0].Level != "debug" || info.Outputs[1].Level != "error" {
+		t.Fatalf("unexpected logger info: %+v", info)
+	}
+	Use("admin_multi").Debug("debug log")
+	if buf1.String() != "[DEBUG] debug log\n" || buf2.Len() != 0 {
+		t.Fatalf("unexpected logs: %q, %q", buf1.String(), buf2.String())
+	}
+	multi := MustUse("admin_multi")
+	deadline = time.Now().Add(2 * time.Second)
+	for multi.Enabled(def.LogLevelDebug) {
+		if time.Now().After(deadline) {
+			t.Fatal("config not reverted")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	multi.Error("error log")
+	if !strings.HasSuffix(buf1.String(), "[ERROR] error log\n") || buf2.String() != "[ERROR] error log\n" {
+		t.Fatalf("unexpected logs: %q, %q", buf1.String(), buf2.String())
+	}
+
+	// 错误的请求
+	for query, expect := range map[string]int{
+		"name=missing&level=debug":      http.StatusNotFound,
+		"name=admin":                    http.StatusBadRequest,
+		"name=admin&level=verbose":      http.StatusBadRequest,
+		"name=admin&switch=maybe":       http.StatusBadRequest,
+		"name=admin&level=info&ttl=abc": http.StatusBadRequest,
+	} {
+		if code := request(http.MethodPut, query, nil); code != expect {
+			t.Errorf("%s: expect status %d, got %d", query, expect, code)
+		}
+	}
+	if code := request(http.MethodDelete, "name=admin", nil); code != http.StatusMethodNotAllowed {
+		t.Errorf("expect status %d, got %d", http.StatusMethodNotAllowed, code)
+	}
+}
+
+// 测试使用环境变量覆盖配置
+func TestApplyEnv(t *testing.T) {
+	envs := map[string]string{
+		"XLOGT_LEVEL":             "info",
+		"XLOGT_OUTPUT":            "stderr",
+		"XLOGT_MAX_SIZE":          "10MB",
+		"XLOGT_ASYNC":             "true",
+		"XLOGT_ORDER_LEVEL":       "ERROR",
+		"XLOGT_ORDER_OUTPUT":      "file",
+		"XLOGT_API_V1_SWITCH":     "off",
+		"XLOGT_API_V1_QUEUE_SIZE": "64",
+	}
+	for k, v := range envs {
+		_ = os.Setenv(k, v)
+		defer os.Unsetenv(k)
+	}
+
+	cfg, err := ConfigFromEnv("xlogt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.LogLevel != "info" || cfg.Output != "stderr" || cfg.MaxSize != "10MB" || !cfg.Async || cfg.Rotate != "date" {
+		t.Fatalf("unexpected config: %+v", cfg)
+	}
+
+	// 指定日志对象的环境变量优先于全局的环境变量，全局的环境变量优先于代码中的配置
+	code := &Config{LogLevel: "debug", Output: "stdout", LogPrefix: "order_"}
+	order, err := ApplyEnv(code, "XLOGT", "order")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if order.LogLevel != "error" || order.Output != "file" || order.LogPrefix != "order_" {
+		t.Fatalf("unexpected config: %+v", order)
+	}
+	if code.LogLevel != "debug" {
+		t.Fatal("original config should not be modified")
+	}
+
+	fc := &FileConfig{Loggers: map[string]*Config{"api.v1": {LogLevel: "warn"}}}
+	if err := fc.ApplyEnv("XLOGT"); err != nil {
+		t.Fatal(err)
+	}
+	api := fc.Loggers["api.v1"]
+	if api.LogLevel != "info" || api.Switch != "off" || api.QueueSize != 64 {
+		t.Fatalf("unexpected config: %+v", api)
+	}
+
+	// 无效的值
+	for k, v := range map[string]string{
+		"XLOGT_BAD_LEVEL":       "verbose",
+		"XLOGT_BAD_MAX_SIZE":    "10XB",
+		"XLOGT_BAD_ASYNC":       "maybe",
+		"XLOGT_BAD_MAX_BACKUPS": "-1",
+	} {
+		_ = os.Setenv(k, v)
+		if _, err := ApplyEnv(nil, "XLOGT", "bad"); err == nil || !strings.Contains(err.Error(), k) {
+			t.Errorf("%s=%s: expect error, got %v", k, v, err)
+		}
+		_ = os.Unsetenv(k)
+	}
+
+	// 名称有多种解释时按最长的配置项匹配
+	_ = os.Setenv("XLOGT_ORDER_STACK_LEVEL", "none")
+	defer os.Unsetenv("XLOGT_ORDER_STACK_LEVEL")
+	if order, _ = ApplyEnv(&Config{LogLevel: "warn"}, "XLOGT", "order"); order.LogStackLevel != "none" {
+		t.Fatalf("unexpected config: %+v", order)
+	}
+	stack, err := ApplyEnv(&Config{LogLevel: "warn"}, "XLOGT", "order.stack")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stack.LogLevel != "info" || stack.LogStackLevel != "" {
+		t.Fatalf("unexpected config: %+v", stack)
+	}
+
+	// 与全局的环境变量同名时返回错误
+	_ = os.Setenv("XLOGT_TIME_FORMAT", "RFC3339")
+	defer os.Unsetenv("XLOGT_TIME_FORMAT")
+	if _, err := ApplyEnv(nil, "XLOGT", "order"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ApplyEnv(nil, "XLOGT", "time"); err == nil || !strings.Contains(err.Error(), "ambiguous") {
+		t.Fatalf("expect ambiguous error, got %v", err)
+	}
+}
+
+// 测试加载以及重新加载配置文件时使用环境变量覆盖
+func TestConfigFileEnv(t *testing.T) {
+	dir, err := ioutil.TempDir("", "xlog")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "env.yaml")
+	writeConfig := func(content string, mtime time.Time) {
+		if err := ioutil.WriteFile(path, []byte(content), 0666); err != nil {
+			t.Fatal(err)
+		}
+		_ = os.Chtimes(path, mtime, mtime)
+	}
+	_ = os.Setenv("XLOG_ENV_API_LEVEL", "debug")
+	defer os.Unsetenv("XLOG_ENV_API_LEVEL")
+	defer Clear()
+
+	now := time.Now()
+	writeConfig("loggers:\n  env.api:\n    output: stdout\n    log_level: error\n", now.Add(-time.Hour))
+	if err := InitFromFile(path); err != nil {
+		t.Fatal(err)
+	}
+	if d, _ := Describe("env.api"); d.Level != def.LevelDebug {
+		t.Fatalf("env override not applied, level %d", d.Level)
+	}
+
+	stop, err := WatchConfigFile(path, 10*time.Millisecond, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stop()
+	// 重新加载后仍然使用环境变量覆盖
+	writeConfig("loggers:\n  env.api:\n    output: stdout\n    log_level: error\n    format: json\n", now)
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		d, _ := Describe("env.api")
+		if d.Format == def.FormatJson {
+			if d.Level != def.LevelDebug {
+				t.Fatalf("env override lost after reload, level %d", d.Level)
+			}
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("config not reloaded")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// 测试配置校验以及返回错误的注册方法
+func TestValidate(t *testing.T) {
+	if err := DefaultConfig().Validate(); err != nil {
+		t.Fatal(err)
+	}
+	var nilCfg *Config
+	if err := nilCfg.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	cfg := &Config{
+		LogLevel: "warning",
+		Rotate:   "daily",
+		MaxSize:  "10XB",
+		Outputs:  []*OutputConfig{{Output: "syslog"}},
+	}
+	err := cfg.Validate()
+	if err == nil {
+		t.Fatal("expect validation error")
+	}
+	for _, s := range []string{`log_level "warning"`, `rotate "daily"`, `max_size "10XB"`, `outputs[0].output "syslog"`} {
+		if !strings.Contains(err.Error(), s) {
+			t.Errorf("error should contain %s: %v", s, err)
+		}
+	}
+	if err := RegisterE("invalid", cfg); err == nil || MustUse("invalid") != nil {
+		t.Fatal("invalid config should not be registered")
+	}
+
+	// 日志目录无法写入
+	dir, err := ioutil.TempDir("", "xlog")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	notDir := filepath.Join(dir, "file")
+	_ = ioutil.WriteFile(notDir, nil, 0666)
+	bad := &Config{LogPath: filepath.Join(notDir, "logs"), Output: "file", LogLevel: "info"}
+	if err := RegisterE("unwritable", bad); err == nil || MustUse("unwritable") != nil {
+		t.Fatal("logger with unwritable directory should not be registered")
+	}
+
+	// 更新失败时保持原有的配置
+	good := &Config{LogPath: dir, LogPrefix: "valid_", Output: "file", LogLevel: "info", Rotate: "none"}
+	if err := RegisterE("valid", good); err != nil {
+		t.Fatal(err)
+	}
+	defer Clear()
+	l := MustUse("valid")
+	file := l.CurrentLogFile()
+	if err := RegisterE("valid", bad); err == nil {
+		t.Fatal("expect error")
+	}
+	if l.CurrentLogFile() != file || !l.Enabled(def.LogLevelInfo) {
+		t.Fatal("config should be kept after failed update")
+	}
+	if err := InitE(&Config{Output: "stdout", LogLevel: "info", Format: "xml"}); err == nil {
+		t.Fatal("expect error")
+	}
+
+	// 无效的输出类型使用标准输出
+	old := std.outputType
+	SetLogOutputType(100)
+	if std.outputType != def.LogToStdout {
+		t.Fatalf("expect output type %d, got %d", def.LogToStdout, std.outputType)
+	}
+	std.outputType = old
+}
+
+// 测试日志格式标签、时间格式以及时区
+func TestFlagsAndTimeFormat(t *testing.T) {
+	buf := &bytes.Buffer{}
+	l := NewStdLogger(&Config{
+		LogLevel:      "debug",
+		LogStackLevel: "none",
+		Sink:          NewWriterSink(buf),
+		Flags:         "date,time,shortfile",
+		TimeFormat:    "RFC3339",
+		TimeZone:      "UTC",
+	})
+	l.Info("time format")
+	line := buf.String()
+	parts := strings.SplitN(line, " ", 2)
+	ts, err := time.Parse(time.RFC3339, parts[0])
+	if err != nil || !strings.HasSuffix(parts[0], "Z") {
+		t.Fatalf("unexpected time %q: %v", parts[0], err)
+	}
+	if time.Since(ts) > time.Minute {
+		t.Fatalf("unexpected time %q", parts[0])
+	}
+	if !strings.HasPrefix(parts[1], "[INFO] xlog_test.go:") || !strings.HasSuffix(line, ": time format\n") {
+		t.Fatalf("unexpected log: %q", line)
+	}
+
+	// 不输出时间以及调用位置
+	buf.Reset()
+	l = NewStdLogger(&Config{LogLevel: "debug", LogStackLevel: "none", Sink: NewWriterSink(buf), Flags: "none"})
+	l.Info("no flags")
+	if buf.String() != "[INFO] no flags\n" {
+		t.Fatalf("unexpected log: %q", buf.String())
+	}
+
+	// 结构化日志使用指定的时间格式
+	buf.Reset()
+	l = NewStdLogger(&Config{
+		LogLevel:      "debug",
+		LogStackLevel: "none",
+		Sink:          NewWriterSink(buf),
+		Format:        "json",
+		TimeFormat:    "2006-01-02 15:04:05 MST",
+		TimeZone:      "UTC",
+	})
+	l.Info("json time")
+	var m map[string]interface{}
+	if err := json.Unmarshal(buf.Bytes(), &m); err != nil {
+		t.Fatal(err)
+	}
+	if s, _ := m["time"].(string); !strings.HasSuffix(s, " UTC") {
+		t.Fatalf("unexpected time: %v", m["time"])
+	}
+
+	if err := (&Config{Flags: "date,nanoseconds", TimeZone: "Mars/Olympus"}).Validate(); err == nil ||
+		!strings.Contains(err.Error(), "nanoseconds") || !strings.Contains(err.Error(), "Mars/Olympus") {
+		t.Fatalf("unexpected validation error: %v", err)
+	}
+}
+
+// 测试trace等级以及自定义日志等级
+func TestCustomLevel(t *testing.T) {
+	buf := &bytes.Buffer{}
+	l := NewStdLogger(&Config{LogLevel: "trace", LogStackLevel: "none", Sink: NewWriterSink(buf), Flags: "none"})
+	l.Trace("trace log")
+	if buf.String() != "[TRACE] trace log\n" {
+		t.Fatalf("unexpected log: %q", buf.String())
+	}
+	if NewStdLogger(&Config{LogLevel: "debug", Sink: NewWriterSink(buf)}).Enabled(def.LogLevelTrace) {
+		t.Fatal("trace should be filtered by debug level")
+	}
+
+	if err := RegisterLevel(CustomLevel{Name: "info", Severity: 10}); err == nil {
+		t.Fatal("builtin level should not be registered")
+	}
+	if err := RegisterLevel(CustomLevel{Name: "audit", Severity: 10, Display: "AUDIT", Color: 36}); err != nil {
+		t.Fatal(err)
+	}
+	if err := RegisterLevel(CustomLevel{Name: "notice", Severity: def.LevelInfo + 1}); err != nil {
+		t.Fatal(err)
+	}
+	cfg := &Config{LogLevel: "audit", LogStackLevel: "none", Sink: NewWriterSink(buf), Flags: "none"}
+	if err := cfg.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	buf.Reset()
+	l = NewStdLogger(cfg)
+	l.Error("error log")
+	l.Log("audit", "audit log")
+	if buf.String() != "[AUDIT] audit log\n" {
+		t.Fatalf("unexpected log: %q", buf.String())
+	}
+
+	// 自定义等级的数值介于内置等级之间
+	buf.Reset()
+	l = NewStdLogger(&Config{LogLevel: "notice", LogStackLevel: "none", Sink: NewWriterSink(buf), Format: "json"})
+	l.Info("info log")
+	l.Log("notice", "notice log")
+	l.Warn("warn log")
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 || !strings.Contains(lines[0], `"level":"notice"`) {
+		t.Fatalf("unexpected log: %q", buf.String())
+	}
+
+	// 彩色打印
+	var out []byte
+	encodeText(&out, &LogDefinition{}, &logEntry{level: "audit", msg: "colorful"}, true)
+	if string(out) != "\x1b[36m[AUDIT] colorful\n\x1b[0m" {
+		t.Fatalf("unexpected colorful log: %q", out)
+	}
+
+	if err := (&Config{LogLevel: "verbose"}).Validate(); err == nil {
+		t.Fatal("unregistered level should be invalid")
+	}
+
+	// 使用未注册的日志等级时按照error等级记录，并通过诊断回调报告一次
+	var reported []error
+	SetDiagnosticHook(func(err error) {
+		reported = append(reported, err)
+	})
+	defer SetDiagnosticHook(nil)
+	buf.Reset()
+	l = NewStdLogger(&Config{LogLevel: "debug", LogStackLevel: "none", Sink: NewWriterSink(buf), Flags: "none"})
+	l.Log("verbose", "misfiled")
+	l.Logf("verbose", "misfiled %d", 2)
+	l.Log("audit", "registered")
+	if buf.String() != "[VERBOSE] misfiled\n[VERBOSE] misfiled 2\n[AUDIT] registered\n" {
+		t.Fatalf("unexpected log: %q", buf.String())
+	}
+	if len(reported) != 1 || !errors.Is(reported[0], ErrUnknownLevel) || !strings.Contains(reported[0].Error(), `"verbose"`) {
+		t.Fatalf("unexpected reports: %v", reported)
+	}
+}
+
+// 测试按调用位置设置日志等级
+func TestVModule(t *testing.T) {
+	buf := &bytes.Buffer{}
+	newLogger := func(level, vmodule string) *StdLogger {
+		buf.Reset()
+		return NewStdLogger(&Config{
+			LogLevel:      level,
+			VModule:       vmodule,
+			LogStackLevel: "none",
+			Sink:          NewWriterSink(buf),
+			Flags:         "none",
+		})
+	}
+
+	// 匹配文件名，提高日志等级
+	l := newLogger("error", "nomatch/*=trace, xlog_test=debug")
+	l.Trace("trace log")
+	l.Debug("debug log")
+	l.DebugContext(context.Background(), "debug context log")
+	if buf.String() != "[DEBUG] debug log\n[DEBUG] debug context log\n" {
+		t.Fatalf("unexpected log: %q", buf.String())
+	}
+
+	// 匹配包路径以及文件路径
+	dir := filepath.Base(filepath.Dir(callerFile(t)))
+	for _, vmodule := range []string{dir + "=trace", dir + "/*.go=trace", "*/xlog_test.go=trace"} {
+		l = newLogger("error", vmodule)
+		l.Trace("trace log")
+		if buf.String() != "[TRACE] trace log\n" {
+			t.Fatalf("%s: unexpected log: %q", vmodule, buf.String())
+		}
+	}
+
+	// Enabled同样按调用位置判断，使用Enabled判断后记录的日志不会丢失
+	l = newLogger("info", "xlog_test=debug")
+	if l.Enabled("debug") {
+		l.Debug("guarded debug log")
+	}
+	if buf.String() != "[DEBUG] guarded debug log\n" || l.Enabled("trace") {
+		t.Fatalf("unexpected log: %q", buf.String())
+	}
+	Init(&Config{LogLevel: "info", VModule: "xlog_test=debug", LogStackLevel: "none", Sink: NewWriterSink(buf), Flags: "none"})
+	defer Clear()
+	if !Enabled("debug") || Enabled("trace") {
+		t.Fatal("expect Enabled to match the caller of the package function")
+	}
+
+	// 不匹配的规则不影响其他位置的日志
+	l = newLogger("error", "payment/*=debug")
+	l.Debug("debug log")
+	if buf.Len() != 0 || l.Enabled("debug") {
+		t.Fatalf("unexpected log: %q", buf.String())
+	}
+
+	// 降低日志等级，同样使用缓存判断
+	l = newLogger("debug", "xlog_test.go=error")
+	allocs := testing.AllocsPerRun(100, func() {
+		l.Infof("filtered info log: %d", 42)
+	})
+	l.Error("error log")
+	if buf.String() != "[ERROR] error log\n" {
+		t.Fatalf("unexpected log: %q", buf.String())
+	}
+	if allocs != 0 {
+		t.Fatalf("expect 0 allocs for filtered logs, got %v", allocs)
+	}
+
+	// 多个输出目标时，各个输出目标自身的日志等级仍然有效
+	buf1, buf2 := &bytes.Buffer{}, &bytes.Buffer{}
+	l = NewStdLogger(&Config{
+		VModule:       "xlog_test=debug",
+		LogStackLevel: "none",
+		Flags:         "none",
+		Outputs: []*OutputConfig{
+			{Sink: NewWriterSink(buf1), LogLevel: "info"},
+			{Sink: NewWriterSink(buf2), LogLevel: "error"},
+		},
+	})
+	l.Debug("debug log")
+	l.Info("info log")
+	l.Error("error log")
+	if buf1.String() != "[INFO] info log\n[ERROR] error log\n" || buf2.String() != "[ERROR] error log\n" {
+		t.Fatalf("unexpected logs: %q, %q", buf1.String(), buf2.String())
+	}
+
+	for _, vmodule := range []string{"payment", "payment=verbose", "[=debug"} {
+		if err := (&Config{VModule: vmodule}).Validate(); err == nil {
+			t.Errorf("%s: expect validation error", vmodule)
+		}
+	}
+}
+
+// callerFile 返回调用者所在的文件
+func callerFile(t *testing.T) string {
+	_, file, _, ok := runtime.Caller(1)
+	if !ok {
+		t.Fatal("get caller failed")
+	}
+	return file
+}
+
+// 测试按名称分级的日志对象以及配置继承
+func TestLoggerHierarchy(t *testing.T) {
+	defer Clear()
+	buf := &bytes.Buffer{}
+	err := RegisterE("order", &Config{
+		LogLevel:      "info",
+		LogStackLevel: "none",
+		Sink:          NewWriterSink(buf),
+		Flags:         "none",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 未注册的下级日志对象使用最近的上级日志对象
+	if Use("order.payment.refund") != MustUse("order") {
+		t.Fatal("expect unregistered child to resolve to its ancestor")
+	}
+	if Use("orders") != defaultLogger || Use("payment.order") != defaultLogger {
+		t.Fatal("expect unrelated name to resolve to default logger")
+	}
+
+	// 下级日志对象只覆盖部分配置，其余配置继承上级
+	if err := RegisterE("order.payment", &Config{LogLevel: "debug", Format: "logfmt"}); err != nil {
+		t.Fatal(err)
+	}
+	Use("order.payment.refund").Debug("refund")
+	Use("order").Debug("order debug")
+	if !strings.HasSuffix(buf.String(), " level=debug msg=refund logger=order.payment\n") || strings.Count(buf.String(), "\n") != 1 {
+		t.Fatalf("unexpected log: %q", buf.String())
+	}
+
+	// 上级配置变化后，下级日志对象重新继承
+	buf2 := &bytes.Buffer{}
+	Register("order", &Config{
+		LogLevel:      "warn",
+		LogStackLevel: "none",
+		Sink:          NewWriterSink(buf2),
+		Flags:         "none",
+		Switch:        "on",
+	})
+	Use("order.payment").Debug("payment debug")
+	if !strings.HasSuffix(buf2.String(), " level=debug msg=\"payment debug\" logger=order.payment\n") {
+		t.Fatalf("unexpected log: %q", buf2.String())
+	}
+
+	// 先注册下级再注册上级，下级同样继承
+	buf3 := &bytes.Buffer{}
+	Register("user.login", &Config{LogLevel: "error"})
+	Register("user", &Config{LogStackLevel: "none", Sink: NewWriterSink(buf3), Flags: "none"})
+	Use("user.login").Warn("filtered")
+	Use("user.login").Error("login failed")
+	if buf3.String() != "[ERROR] login failed\n" {
+		t.Fatalf("unexpected log: %q", buf3.String())
+	}
+
+	// 关闭上级的日志对象时，下级一同关闭，除非下级重新开启
+	Register("user", &Config{LogStackLevel: "none", Sink: NewWriterSink(buf3), Flags: "none", Switch: "off"})
+	Register("user.logout", &Config{Switch: "on"})
+	buf3.Reset()
+	Use("user.login").Error("login failed")
+	Use("user.logout").Error("logout failed")
+	if buf3.String() != "[ERROR] logout failed\n" {
+		t.Fatalf("unexpected log: %q", buf3.String())
+	}
+}
+
+// 测试下级日志对象共享上级日志对象打开的日志文件
+func TestLoggerHierarchyFileOutput(t *testing.T) {
+	defer Clear()
+	dir, err := ioutil.TempDir("", "xlog-hierarchy")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	readLog := func(name string) string {
+		data, _ := ioutil.ReadFile(filepath.Join(dir, name))
+		return string(data)
+	}
+
+	orderCfg := &Config{Output: "file", LogPath: dir, LogPrefix: "order", LogLevel: "info", LogStackLevel: "none", Rotate: "none", Flags: "none"}
+	if err := RegisterE("order", orderCfg); err != nil {
+		t.Fatal(err)
+	}
+	// 未设置输出选项的下级日志对象共享上级的日志文件，不重复打开
+	if err := RegisterE("order.payment", &Config{LogLevel: "debug"}); err != nil {
+		t.Fatal(err)
+	}
+	parent, child := MustUse("order"), MustUse("order.payment")
+	if len(child.Sinks()) != 1 || child.Sinks()[0] != parent.Sinks()[0] {
+		t.Fatal("expect child to share the parent's file sink")
+	}
+	if d, _ := Describe("order.payment"); d.OutputType != def.LogToFile || d.FilePrefix != "order" {
+		t.Fatalf("unexpected definition: %+v", d)
+	}
+	child.Debug("payment debug")
+	parent.Debug("order debug")
+	parent.Info("order info")
+	if got := readLog("orderall.log"); got != "[DEBUG] payment debug\n[INFO] order info\n" {
+		t.Fatalf("unexpected log: %q", got)
+	}
+
+	// 只覆盖部分输出选项的下级日志对象，其余输出选项仍然继承上级的配置
+	if err := RegisterE("order.refund", &Config{LogPrefix: "refund"}); err != nil {
+		t.Fatal(err)
+	}
+	refund := MustUse("order.refund")
+	if refund.CurrentLogFile() != filepath.Join(dir, "refundall.log") || refund.Sinks()[0] == parent.Sinks()[0] {
+		t.Fatalf("unexpected log file: %q", refund.CurrentLogFile())
+	}
+	refund.Info("refund")
+	if readLog("refundall.log") != "[INFO] refund\n" || strings.Contains(readLog("orderall.log"), "refund") {
+		t.Fatalf("unexpected log: %q", readLog("refundall.log"))
+	}
+	// 写入同一个日志文件时共享上级的输出目标
+	if err := RegisterE("order.notify", &Config{MaxSize: "10MB"}); err != nil {
+		t.Fatal(err)
+	}
+	if d, _ := Describe("order.notify"); d.OutputType != def.LogToFile || d.Dir != dir || MustUse("order.notify").Sinks()[0] != parent.Sinks()[0] {
+		t.Fatalf("unexpected definition: %+v", d)
+	}
+
+	// 通过Reset关闭上级开启的选项
+	cfg := *orderCfg
+	cfg.LogPrefix, cfg.ColorfulPrint, cfg.WatchFile, cfg.MaxBackups = "user", true, true, 3
+	Register("user", &cfg)
+	Register("user.login", &Config{Reset: []string{"colorful_print", "watch_file", "max_backups"}})
+	Register("user.login.retry", &Config{LogLevel: "debug"})
+	for _, k := range []string{"user.login", "user.login.retry"} {
+		if d, _ := Describe(k); d.ColorfulPrint || d.WatchFile || d.MaxBackups != 0 || d.OutputType != def.LogToFile {
+			t.Fatalf("unexpected definition of %s: %+v", k, d)
+		}
+	}
+	if err := RegisterE("user.logout", &Config{Reset: []string{"colorful"}}); err == nil {
+		t.Fatal("expect unknown reset field rejected")
+	}
+
+	// 移除或者关闭下级日志对象不会关闭上级的日志文件
+	if err := Unregister("order.payment"); err != nil {
+		t.Fatal(err)
+	}
+	Register("order.payment", nil)
+	_ = MustUse("order.payment").Close()
+	parent.Info("still open")
+	if !strings.HasSuffix(readLog("orderall.log"), "[INFO] still open\n") {
+		t.Fatalf("unexpected log: %q", readLog("orderall.log"))
+	}
+
+	// 上级日志文件变化后，下级日志对象共享新的日志文件
+	Register("order.payment.refund", &Config{LogLevel: "warn"})
+	orderCfg.LogPrefix = "order2"
+	if err := RegisterE("order", orderCfg); err != nil {
+		t.Fatal(err)
+	}
+	sink := parent.Sinks()[0]
+	if MustUse("order.payment").Sinks()[0] != sink || MustUse("order.payment.refund").Sinks()[0] != sink {
+		t.Fatal("expect children to share the parent's new file sink")
+	}
+	MustUse("order.payment.refund").Warn("refund warn")
+	if got := readLog("order2all.log"); got != "[WARN] refund warn\n" {
+		t.Fatalf("unexpected log: %q", got)
+	}
+}
+
+// 测试查看、移除日志对象以及严格模式
+func TestRegistryIntrospection(t *testing.T) {
+	defer Clear()
+	dir, err := ioutil.TempDir("", "xlog-registry")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	Register("order", &Config{Output: "file", LogPath: dir, LogPrefix: "order", LogLevel: "info", Rotate: "none"})
+	Register("order.payment", &Config{LogLevel: "debug"})
+	Register("user", &Config{LogLevel: "warn"})
+	if names := Names(); !reflect.DeepEqual(names, []string{"order", "order.payment", "user"}) {
+		t.Fatalf("unexpected names: %v", names)
+	}
+
+	// 查看合并上级配置后的定义
+	d, ok := Describe("order.payment.refund")
+	if !ok || d.Level != def.LevelDebug || d.OutputType != def.LogToFile || d.FilePrefix != "order" {
+		t.Fatalf("unexpected definition: %+v", d)
+	}
+	d.Level = def.LevelFatal
+	if d, _ := Describe("order.payment"); d.Level != def.LevelDebug {
+		t.Fatal("expect Describe to return a copy")
+	}
+	if _, ok := Describe("ordr"); ok {
+		t.Fatal("expect unknown logger")
+	}
+
+	// 移除后关闭日志文件，下级日志对象不再继承
+	l := MustUse("order")
+	if err := Unregister("order"); err != nil {
+		t.Fatal(err)
+	}
+	if MustUse("order") != nil || len(l.Sinks()) != 0 {
+		t.Fatal("expect logger removed and closed")
+	}
+	if d, _ := Describe("order.payment"); d.OutputType != def.LogToStdout {
+		t.Fatalf("expect child to stop inheriting, got output type %d", d.OutputType)
+	}
+	if err := Unregister("order"); err != nil {
+		t.Fatal(err)
+	}
+
+	// 严格模式下未注册的名称只报告一次
+	var reported []error
+	SetDiagnosticHook(func(err error) {
+		reported = append(reported, err)
+	})
+	defer SetDiagnosticHook(nil)
+	Use("ordr")
+	SetStrict(true)
+	defer SetStrict(false)
+	Use("ordr")
+	Use("ordr")
+	Use("order.payment.refund")
+	Use("default")
+	if len(reported) != 1 || !errors.Is(reported[0], ErrUnknownLogger) || !strings.Contains(reported[0].Error(), `"ordr"`) {
+		t.Fatalf("unexpected reports: %v", reported)
+	}
+}
+
+// 测试关闭全部日志对象以及Fatal退出前写入缓存的日志
+func TestShutdown(t *testing.T) {
+	defer Clear()
+	var codes []int
+	SetExitFunc(func(code int) {
+		codes = append(codes, code)
+	})
+	defer SetExitFunc(nil)
+
+	// 未注册的异步日志对象在退出前写入全部日志
+	buf := &bytes.Buffer{}
+	l := NewStdLogger(&Config{
+		LogLevel:      "debug",
+		LogStackLevel: "none",
+		Sink:          NewWriterSink(buf),
+		Flags:         "none",
+		Async:         true,
+	})
+	for i := 0; i < 100; i++ {
+		l.Infof("async log %d", i)
+	}
+	l.Fatal("fatal log")
+	if n := strings.Count(buf.String(), "\n"); n != 101 || !strings.HasSuffix(buf.String(), "[FATAL] fatal log\n") {
+		t.Fatalf("expect 101 lines ending with fatal log, got %d: %q", n, buf.String())
+	}
+
+	// 包级别的Fatal关闭全部已注册的日志对象
+	dir, err := ioutil.TempDir("", "xlog-shutdown")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	Register("default", &Config{Output: "file", LogPath: dir, LogPrefix: "app", LogLevel: "info", Rotate: "none", LogStackLevel: "none", Async: true})
+	Fatalf("fatal: %d", 42)
+	data, err := ioutil.ReadFile(filepath.Join(dir, "appall.log"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), "[FATAL] ") || !strings.HasSuffix(string(data), "fatal: 42\n") {
+		t.Fatalf("unexpected log file: %q", data)
+	}
+	if !reflect.DeepEqual(codes, []int{1, 1}) {
+		t.Fatalf("unexpected exit codes: %v", codes)
+	}
+
+	// 输出目标阻塞时到期返回
+	sink := newGateSink()
+	Register("slow", &Config{LogLevel: "info", LogStackLevel: "none", Sink: sink, Async: true})
+	Use("slow").Info("blocked")
+	<-sink.started
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*50)
+	defer cancel()
+	if err := Shutdown(ctx); err != context.DeadlineExceeded {
+		t.Fatalf("expect deadline exceeded, got %v", err)
+	}
+	close(sink.release)
+	if err := Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if len(sink.lines) != 1 {
+		t.Fatalf("unexpected lines: %v", sink.lines)
+	}
+}
+
+// 测试相互独立的注册表
+func TestRegistry(t *testing.T) {
+	r1, r2 := NewRegistry(), NewRegistry()
+	defer r1.Clear()
+	defer r2.Clear()
+
+	// 默认设置互不影响，也不影响默认注册表
+	stdLevel := std.level
+	r1.SetLogLevel(def.LogLevelError)
+	r2.SetLogLevel(def.LogLevelDebug)
+	r1.SetLogDir("./r1_logs")
+	defer os.RemoveAll("./r1_logs")
+	r1.Register("plain", nil)
+	r2.Register("plain", nil)
+	if d, _ := r1.Describe("plain"); d.Level != def.LevelError || d.Dir != "./r1_logs" {
+		t.Fatalf("unexpected definition: %+v", d)
+	}
+	if d, _ := r2.Describe("plain"); d.Level != def.LevelDebug || d.Dir != "./logs" {
+		t.Fatalf("unexpected definition: %+v", d)
+	}
+	if LogDir == "./r1_logs" || std.level != stdLevel {
+		t.Fatal("expect default registry unchanged")
+	}
+
+	// 同名的日志对象互不影响
+	buf1, buf2 := &bytes.Buffer{}, &bytes.Buffer{}
+	r1.Register("order", &Config{LogLevel: "info", LogStackLevel: "none", Sink: NewWriterSink(buf1), Flags: "none"})
+	if err := r2.RegisterE("order", &Config{LogLevel: "warn", LogStackLevel: "none", Sink: NewWriterSink(buf2), Flags: "none"}); err != nil {
+		t.Fatal(err)
+	}
+	r1.Use("order.payment").Info("r1 info")
+	r2.Use("order").Info("r2 info")
+	r2.Use("order").Warn("r2 warn")
+	if buf1.String() != "[INFO] r1 info\n" || buf2.String() != "[WARN] r2 warn\n" {
+		t.Fatalf("unexpected logs: %q, %q", buf1.String(), buf2.String())
+	}
+	if MustUse("order") != nil || !reflect.DeepEqual(r1.Names(), []string{"order", "plain"}) {
+		t.Fatal("expect registries isolated")
+	}
+
+	// 查看以及修改日志对象只作用于对应的注册表
+	req := httptest.NewRequest(http.MethodPut, "/debug/xlog?name=order&level=debug", nil)
+	w := httptest.NewRecorder()
+	r1.AdminHandler().ServeHTTP(w, req)
+	if w.Code != http.StatusOK || !r1.MustUse("order").Enabled("debug") || r2.MustUse("order").Enabled("debug") {
+		t.Fatalf("unexpected admin result: %d %s", w.Code, w.Body.String())
+	}
+
+	// 严格模式
+	var reported []error
+	r2.SetStrict(true)
+	r2.SetDiagnosticHook(func(err error) {
+		reported = append(reported, err)
+	})
+	r1.Use("ordr")
+	r2.Use("ordr")
+	if len(reported) != 1 {
+		t.Fatalf("unexpected reports: %v", reported)
+	}
+
+	// 临时修改只记录在日志对象所属的注册表中
+	r2.adminUpdate(r2.MustUse("order"), def.LogLevelError, "", time.Hour)
+	if len(r2.adminReverts) != 1 || len(r1.adminReverts) != 0 || len(std.adminReverts) != 0 {
+		t.Fatal("expect admin reverts kept per registry")
+	}
+	r2.adminUpdate(r2.MustUse("order"), def.LogLevelWarn, "", 0)
+
+	// Fatal关闭日志对象所属注册表中的全部日志对象，并使用该注册表的退出方法
+	var code int
+	SetExitFunc(func(c int) {
+		t.Fatal("unexpected exit of default registry")
+	})
+	defer SetExitFunc(nil)
+	r2.SetExitFunc(func(c int) {
+		code = c
+	})
+	r2.Use("order").Fatal("r2 fatal")
+	if code != 1 || !strings.HasSuffix(buf2.String(), "[FATAL] r2 fatal\n") {
+		t.Fatalf("unexpected fatal: %d %q", code, buf2.String())
+	}
+	if len(r2.MustUse("order").Sinks()) != 0 || len(r1.MustUse("order").Sinks()) != 1 {
+		t.Fatal("expect only r2 loggers closed")
+	}
+
+	// 清除一个注册表不影响其他注册表
+	r2.Clear()
+	if r2.MustUse("order") != nil || r1.MustUse("order") == nil {
+		t.Fatal("expect only r2 cleared")
+	}
+}